@@ -1,11 +1,43 @@
 package config
 
-import "github.com/Abo-Omar-74/httpServer/internal/database"
+import (
+	"database/sql"
+	"sync"
+	"time"
+
+	"github.com/Abo-Omar-74/httpServer/internal/auth"
+	"github.com/Abo-Omar-74/httpServer/internal/content"
+	"github.com/Abo-Omar-74/httpServer/internal/database"
+	"github.com/Abo-Omar-74/httpServer/internal/health"
+	"github.com/Abo-Omar-74/httpServer/internal/lockout"
+	"github.com/Abo-Omar-74/httpServer/internal/mailer"
+	"github.com/Abo-Omar-74/httpServer/internal/storage"
+)
 
 
 type ApiConfig struct{
   Db *database.Queries
+  // SqlDB is the pool behind Db, for the few handlers that need a transaction.
+  SqlDB *sql.DB
   Platform string
-  JwtSecret string
+  Jwt *auth.JWTManager
   UpgradePremiumKey string
+  PasswordHasher *auth.PasswordHasher
+  Mailer mailer.Mailer
+  BaseURL string
+  EmailVerificationTTL time.Duration
+  PasswordResetTTL time.Duration
+  TOTPIssuer string
+  TwoFactorChallengeTTL time.Duration
+  LoginEmailGuard *lockout.Guard
+  LoginIPGuard *lockout.Guard
+  PostContent *content.Pipeline
+  PostRestoreWindow time.Duration
+  Storage storage.Storage
+  MaxAvatarBytes int64
+  MaxAttachmentBytes int64
+  MaxAttachmentsPerPost int
+  Health *health.Checker
+  // Background tracks work handlers keep doing after they respond, so shutdown can wait for it.
+  Background sync.WaitGroup
 }
\ No newline at end of file
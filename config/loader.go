@@ -0,0 +1,393 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFileEnv names the environment variable that points at a config file when no -config flag is given.
+const ConfigFileEnv = "CONFIG_FILE"
+
+// setting is one leaf field of Settings.
+type setting struct {
+	key    string
+	env    string
+	secret bool
+	value  reflect.Value
+}
+
+// flagValue holds a flag's raw text until Load knows it was set. Bool settings
+// can be given as a bare -name, like the flag package's own bool flags.
+type flagValue struct {
+	raw    string
+	isBool bool
+}
+
+func (v *flagValue) String() string {
+	if v == nil {
+		return ""
+	}
+	return v.raw
+}
+
+func (v *flagValue) Set(raw string) error {
+	v.raw = raw
+	return nil
+}
+
+func (v *flagValue) IsBoolFlag() bool { return v.isBool }
+
+// Load builds the settings from, in increasing priority, the defaults, a YAML or TOML
+// config file, environment variables and the command line flags in args. lookupEnv is
+// usually os.LookupEnv. Every problem found is reported together in the returned error.
+func Load(args []string, lookupEnv func(string) (string, bool)) (*Settings, error) {
+	settings, errs, err := load(args, lookupEnv)
+	if err != nil {
+		return nil, err
+	}
+	errs = append(errs, settings.validate()...)
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid configuration:\n%w", errors.Join(errs...))
+	}
+	return settings, nil
+}
+
+// LoadDB reads the settings from the same sources as Load but only validates the
+// database section, for commands such as migrate that never start the server.
+func LoadDB(args []string, lookupEnv func(string) (string, bool)) (*DBSettings, error) {
+	settings, errs, err := load(args, lookupEnv)
+	if err != nil {
+		return nil, err
+	}
+	errs = append(errs, settings.DB.validate()...)
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid configuration:\n%w", errors.Join(errs...))
+	}
+	return &settings.DB, nil
+}
+
+// load applies every source to the defaults. Flag parse errors, including
+// flag.ErrHelp, come back as err; problems with individual values are collected in errs.
+func load(args []string, lookupEnv func(string) (string, bool)) (*Settings, []error, error) {
+	settings := DefaultSettings()
+	fields := settingsFields(&settings)
+
+	flags := flag.NewFlagSet("httpServer", flag.ContinueOnError)
+	configFile := flags.String("config", "", "path to a YAML or TOML config file (env "+ConfigFileEnv+")")
+	values := make(map[string]*flagValue, len(fields))
+	for _, field := range fields {
+		usage := field.value.Type().String()
+		if field.env != "" {
+			usage += " (env " + field.env + ")"
+		}
+		values[field.key] = &flagValue{isBool: field.value.Kind() == reflect.Bool}
+		flags.Var(values[field.key], field.key, usage)
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	var errs []error
+
+	path := *configFile
+	if path == "" {
+		path, _ = lookupEnv(ConfigFileEnv)
+	}
+	if path != "" {
+		errs = append(errs, applyFile(fields, path)...)
+	}
+
+	for _, field := range fields {
+		if field.env == "" {
+			continue
+		}
+		if raw, ok := lookupEnv(field.env); ok && raw != "" {
+			if err := setFromString(field.value, raw); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", field.env, err))
+			}
+		}
+	}
+
+	flags.Visit(func(f *flag.Flag) {
+		field, ok := findSetting(fields, f.Name)
+		if !ok {
+			return
+		}
+		if err := setFromString(field.value, values[f.Name].raw); err != nil {
+			errs = append(errs, fmt.Errorf("-%s: %w", f.Name, err))
+		}
+	})
+
+	if settings.BaseURL == "" {
+		settings.BaseURL = "http://localhost:" + settings.Port
+	}
+	settings.BaseURL = strings.TrimSuffix(settings.BaseURL, "/")
+	return &settings, errs, nil
+}
+
+// String lists every setting as "key = value", one per line, with secrets redacted.
+func (s Settings) String() string {
+	var b strings.Builder
+	for _, field := range settingsFields(&s) {
+		value := formatValue(field.value)
+		if field.secret && value != "" {
+			value = "[redacted]"
+		}
+		fmt.Fprintf(&b, "%s = %s\n", field.key, value)
+	}
+	return b.String()
+}
+
+func (s *Settings) validate() []error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	port, err := strconv.Atoi(s.Port)
+	check(err == nil && port > 0 && port <= 65535, "port must be a number between 1 and 65535, got %q", s.Port)
+	check(s.Platform == "dev" || s.Platform == "staging" || s.Platform == "prod", "platform must be dev, staging or prod, got %q", s.Platform)
+	check(s.UpgradePremiumKey != "", "upgrade_premium_key (API_KEY_UPGRADE_PREMIUM) is not set")
+
+	errs = append(errs, s.DB.validate()...)
+
+	check(s.HTTP.ReadHeaderTimeout > 0, "http.read_header_timeout must be positive")
+	check(s.HTTP.ShutdownTimeout > 0, "http.shutdown_timeout must be positive")
+	check(s.HTTP.DrainDelay >= 0, "http.drain_delay must not be negative")
+	check(s.HTTP.HealthCheckTimeout > 0, "http.health_check_timeout must be positive")
+
+	check(s.JWT.Secret != "" || len(s.JWT.SigningKeys) > 0, "jwt.secret (JWT_SECRET) or jwt.signing_keys (JWT_SIGNING_KEYS) must be set")
+	check(s.JWT.AccessTTL > 0, "jwt.access_ttl must be positive")
+	check(s.JWT.Leeway >= 0, "jwt.leeway must not be negative")
+
+	hasher := strings.ToLower(s.Password.Hasher)
+	check(hasher == "bcrypt" || hasher == "argon2id", "password.hasher must be bcrypt or argon2id, got %q", s.Password.Hasher)
+	check(s.Password.BcryptCost >= 4 && s.Password.BcryptCost <= 31, "password.bcrypt_cost must be between 4 and 31")
+	check(s.Password.Argon2MemoryKiB > 0, "password.argon2_memory_kib must be positive")
+	check(s.Password.Argon2Iterations > 0, "password.argon2_iterations must be positive")
+	check(s.Password.Argon2Parallelism > 0 && s.Password.Argon2Parallelism <= 255, "password.argon2_parallelism must be between 1 and 255")
+
+	kind := strings.ToLower(s.Mailer.Kind)
+	check(kind == "log" || kind == "file", "mailer.kind must be log or file, got %q", s.Mailer.Kind)
+	check(kind != "file" || s.Mailer.Dir != "", "mailer.dir (MAILER_DIR) must be set for the file mailer")
+
+	check(s.Auth.EmailVerificationTTL > 0, "auth.email_verification_ttl must be positive")
+	check(s.Auth.PasswordResetTTL > 0, "auth.password_reset_ttl must be positive")
+	check(s.Auth.TwoFactorChallengeTTL > 0, "auth.two_factor_challenge_ttl must be positive")
+
+	check(s.Login.AttemptStore == "memory" || s.Login.AttemptStore == "postgres", "login.attempt_store must be memory or postgres, got %q", s.Login.AttemptStore)
+	check(s.Login.FreeAttempts >= 0 && s.Login.IPFreeAttempts >= 0, "login free attempts must not be negative")
+	check(s.Login.LockoutAfter >= 0 && s.Login.IPLockoutAfter >= 0, "login lockout thresholds must not be negative")
+
+	check(s.Posts.MaxLength > 0, "posts.max_length must be positive")
+	check(s.Posts.RestoreWindow >= 0, "posts.restore_window must not be negative")
+	check(s.Posts.PurgeInterval > 0, "posts.purge_interval must be positive")
+
+	check(s.Uploads.MaxAvatarBytes > 0, "uploads.max_avatar_bytes must be positive")
+	check(s.Uploads.MaxAttachmentBytes > 0, "uploads.max_attachment_bytes must be positive")
+	check(s.Uploads.MaxAttachmentsPerPost >= 0, "uploads.max_attachments_per_post must not be negative")
+	return errs
+}
+
+func (s *DBSettings) validate() []error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(s.URL != "", "db.url (DB_URL) is not set")
+	check(s.MaxOpenConns >= 0, "db.max_open_conns must not be negative")
+	check(s.MaxIdleConns >= 0, "db.max_idle_conns must not be negative")
+	check(s.ConnMaxLifetime >= 0, "db.conn_max_lifetime must not be negative")
+	check(s.ConnMaxIdleTime >= 0, "db.conn_max_idle_time must not be negative")
+	check(s.ConnectAttempts > 0, "db.connect_attempts must be positive")
+	check(s.ConnectBackoffBase > 0 && s.ConnectBackoffMax >= s.ConnectBackoffBase, "db.connect_backoff_base must be positive and no more than db.connect_backoff_max")
+	check(s.ConnectTimeout > 0, "db.connect_timeout must be positive")
+	return errs
+}
+
+// applyFile sets the fields named in a config file. Nested tables map to dotted keys.
+func applyFile(fields []setting, path string) []error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return []error{fmt.Errorf("config file: %w", err)}
+	}
+
+	raw := map[string]any{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	case ".toml":
+		err = toml.Unmarshal(data, &raw)
+	default:
+		return []error{fmt.Errorf("config file %s: must end in .yaml, .yml or .toml", path)}
+	}
+	if err != nil {
+		return []error{fmt.Errorf("config file %s: %w", path, err)}
+	}
+
+	flat := map[string]any{}
+	flatten("", raw, flat)
+	keys := make([]string, 0, len(flat))
+	for key := range flat {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var errs []error
+	for _, key := range keys {
+		field, ok := findSetting(fields, key)
+		if !ok {
+			errs = append(errs, fmt.Errorf("config file %s: unknown setting %q", path, key))
+			continue
+		}
+		if err := setFromValue(field.value, flat[key]); err != nil {
+			errs = append(errs, fmt.Errorf("config file %s: %s: %w", path, key, err))
+		}
+	}
+	return errs
+}
+
+func flatten(prefix string, values map[string]any, out map[string]any) {
+	for key, value := range values {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		if nested, ok := value.(map[string]any); ok {
+			flatten(key, nested, out)
+			continue
+		}
+		out[key] = value
+	}
+}
+
+// settingsFields lists the leaf fields of settings in declaration order.
+func settingsFields(settings *Settings) []setting {
+	var fields []setting
+	var walk func(prefix string, v reflect.Value)
+	walk = func(prefix string, v reflect.Value) {
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			key := f.Tag.Get("key")
+			if prefix != "" {
+				key = prefix + "." + key
+			}
+			if f.Type.Kind() == reflect.Struct {
+				walk(key, v.Field(i))
+				continue
+			}
+			fields = append(fields, setting{
+				key:    key,
+				env:    f.Tag.Get("env"),
+				secret: f.Tag.Get("secret") == "true",
+				value:  v.Field(i),
+			})
+		}
+	}
+	walk("", reflect.ValueOf(settings).Elem())
+	return fields
+}
+
+func findSetting(fields []setting, key string) (setting, bool) {
+	for _, field := range fields {
+		if field.key == key {
+			return field, true
+		}
+	}
+	return setting{}, false
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// setFromString parses raw into v. Lists are comma separated.
+func setFromString(v reflect.Value, raw string) error {
+	switch {
+	case v.Type() == durationType:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("must be a duration like 15m: %w", err)
+		}
+		v.SetInt(int64(d))
+	case v.Kind() == reflect.String:
+		v.SetString(raw)
+	case v.Kind() == reflect.Int || v.Kind() == reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return fmt.Errorf("must be an integer, got %q", raw)
+		}
+		v.SetInt(n)
+	case v.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("must be true or false, got %q", raw)
+		}
+		v.SetBool(b)
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.String:
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported setting type %s", v.Type())
+	}
+	return nil
+}
+
+// setFromValue sets v from a decoded YAML or TOML value.
+func setFromValue(v reflect.Value, value any) error {
+	switch value := value.(type) {
+	case string:
+		return setFromString(v, value)
+	case []any:
+		if v.Kind() != reflect.Slice {
+			return fmt.Errorf("must be a single value, not a list")
+		}
+		items := make([]string, 0, len(value))
+		for _, item := range value {
+			items = append(items, fmt.Sprint(item))
+		}
+		v.Set(reflect.ValueOf(items))
+		return nil
+	case int, int64, uint64, float64, bool:
+		if v.Type() == durationType {
+			return fmt.Errorf("must be a duration string like \"15m\"")
+		}
+		return setFromString(v, fmt.Sprint(value))
+	case nil:
+		return nil
+	}
+	return fmt.Errorf("unsupported value %v", value)
+}
+
+func formatValue(v reflect.Value) string {
+	if v.Type() == durationType {
+		return time.Duration(v.Int()).String()
+	}
+	if v.Kind() == reflect.Slice {
+		items := make([]string, v.Len())
+		for i := range items {
+			items[i] = v.Index(i).String()
+		}
+		return strings.Join(items, ",")
+	}
+	return fmt.Sprint(v.Interface())
+}
@@ -0,0 +1,183 @@
+package config
+
+import (
+	"time"
+)
+
+// Settings is the typed startup configuration. Each field has a dotted key used in
+// config files and as a command line flag, and may have an environment variable.
+// Fields tagged secret are redacted when the settings are printed.
+type Settings struct {
+	Port              string `key:"port" env:"PORT"`
+	Platform          string `key:"platform" env:"PLATFORM"`
+	BaseURL           string `key:"base_url" env:"BASE_URL"`
+	MediaDir          string `key:"media_dir" env:"MEDIA_DIR"`
+	UpgradePremiumKey string `key:"upgrade_premium_key" env:"API_KEY_UPGRADE_PREMIUM" secret:"true"`
+
+	DB       DBSettings       `key:"db"`
+	HTTP     HTTPSettings     `key:"http"`
+	JWT      JWTSettings      `key:"jwt"`
+	Password PasswordSettings `key:"password"`
+	Mailer   MailerSettings   `key:"mailer"`
+	Auth     AuthSettings     `key:"auth"`
+	Login    LoginSettings    `key:"login"`
+	Posts    PostSettings     `key:"posts"`
+	Uploads  UploadSettings   `key:"uploads"`
+}
+
+type DBSettings struct {
+	URL             string        `key:"url" env:"DB_URL" secret:"true"`
+	MaxOpenConns    int           `key:"max_open_conns" env:"DB_MAX_OPEN_CONNS"`
+	MaxIdleConns    int           `key:"max_idle_conns" env:"DB_MAX_IDLE_CONNS"`
+	ConnMaxLifetime time.Duration `key:"conn_max_lifetime" env:"DB_CONN_MAX_LIFETIME"`
+	ConnMaxIdleTime time.Duration `key:"conn_max_idle_time" env:"DB_CONN_MAX_IDLE_TIME"`
+	// Startup pings the database up to ConnectAttempts times, doubling the wait
+	// from ConnectBackoffBase up to ConnectBackoffMax between attempts.
+	ConnectAttempts    int           `key:"connect_attempts" env:"DB_CONNECT_ATTEMPTS"`
+	ConnectBackoffBase time.Duration `key:"connect_backoff_base" env:"DB_CONNECT_BACKOFF_BASE"`
+	ConnectBackoffMax  time.Duration `key:"connect_backoff_max" env:"DB_CONNECT_BACKOFF_MAX"`
+	ConnectTimeout     time.Duration `key:"connect_timeout" env:"DB_CONNECT_TIMEOUT"`
+	// MigrateOnStart applies pending schema migrations before the server starts.
+	// Without it the server refuses to start while migrations are pending.
+	MigrateOnStart bool `key:"migrate_on_start" env:"DB_MIGRATE_ON_START"`
+}
+
+type HTTPSettings struct {
+	ReadHeaderTimeout time.Duration `key:"read_header_timeout" env:"HTTP_READ_HEADER_TIMEOUT"`
+	ReadTimeout       time.Duration `key:"read_timeout" env:"HTTP_READ_TIMEOUT"`
+	WriteTimeout      time.Duration `key:"write_timeout" env:"HTTP_WRITE_TIMEOUT"`
+	IdleTimeout       time.Duration `key:"idle_timeout" env:"HTTP_IDLE_TIMEOUT"`
+	// ShutdownTimeout is how long in-flight requests may run after a shutdown signal.
+	ShutdownTimeout time.Duration `key:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT"`
+	// DrainDelay keeps serving after a shutdown signal while /readyz fails, giving
+	// load balancers time to stop sending traffic before the listener closes.
+	DrainDelay         time.Duration `key:"drain_delay" env:"SHUTDOWN_DRAIN_DELAY"`
+	HealthCheckTimeout time.Duration `key:"health_check_timeout" env:"HEALTH_CHECK_TIMEOUT"`
+}
+
+// JWTSettings describe the signing keys. Key lists hold "kid:path" entries of PEM files.
+type JWTSettings struct {
+	Secret           string        `key:"secret" env:"JWT_SECRET" secret:"true"`
+	SigningKeys      []string      `key:"signing_keys" env:"JWT_SIGNING_KEYS"`
+	VerificationKeys []string      `key:"verification_keys" env:"JWT_VERIFICATION_KEYS"`
+	ActiveKeyID      string        `key:"active_key_id" env:"JWT_ACTIVE_KEY_ID"`
+	Issuer           string        `key:"issuer" env:"JWT_ISSUER"`
+	Audience         string        `key:"audience" env:"JWT_AUDIENCE"`
+	AccessTTL        time.Duration `key:"access_ttl" env:"JWT_ACCESS_TTL"`
+	Leeway           time.Duration `key:"leeway" env:"JWT_LEEWAY"`
+}
+
+type PasswordSettings struct {
+	Hasher            string `key:"hasher" env:"PASSWORD_HASHER"`
+	BcryptCost        int    `key:"bcrypt_cost" env:"BCRYPT_COST"`
+	Argon2MemoryKiB   int    `key:"argon2_memory_kib" env:"ARGON2_MEMORY_KIB"`
+	Argon2Iterations  int    `key:"argon2_iterations" env:"ARGON2_ITERATIONS"`
+	Argon2Parallelism int    `key:"argon2_parallelism" env:"ARGON2_PARALLELISM"`
+}
+
+type MailerSettings struct {
+	Kind string `key:"kind" env:"MAILER"`
+	Dir  string `key:"dir" env:"MAILER_DIR"`
+}
+
+type AuthSettings struct {
+	EmailVerificationTTL  time.Duration `key:"email_verification_ttl" env:"EMAIL_VERIFICATION_TTL"`
+	PasswordResetTTL      time.Duration `key:"password_reset_ttl" env:"PASSWORD_RESET_TTL"`
+	TOTPIssuer            string        `key:"totp_issuer" env:"TOTP_ISSUER"`
+	TwoFactorChallengeTTL time.Duration `key:"two_factor_challenge_ttl" env:"TWO_FACTOR_CHALLENGE_TTL"`
+}
+
+// LoginSettings tune the login backoff. The IP guard shares the delays but allows more attempts.
+type LoginSettings struct {
+	AttemptStore    string        `key:"attempt_store" env:"LOGIN_ATTEMPT_STORE"`
+	FreeAttempts    int           `key:"free_attempts" env:"LOGIN_FREE_ATTEMPTS"`
+	LockoutAfter    int           `key:"lockout_after" env:"LOGIN_LOCKOUT_AFTER"`
+	IPFreeAttempts  int           `key:"ip_free_attempts" env:"LOGIN_IP_FREE_ATTEMPTS"`
+	IPLockoutAfter  int           `key:"ip_lockout_after" env:"LOGIN_IP_LOCKOUT_AFTER"`
+	BackoffBase     time.Duration `key:"backoff_base" env:"LOGIN_BACKOFF_BASE"`
+	BackoffMax      time.Duration `key:"backoff_max" env:"LOGIN_BACKOFF_MAX"`
+	LockoutDuration time.Duration `key:"lockout_duration" env:"LOGIN_LOCKOUT_DURATION"`
+	FailureWindow   time.Duration `key:"failure_window" env:"LOGIN_FAILURE_WINDOW"`
+}
+
+type PostSettings struct {
+	MaxLength     int           `key:"max_length" env:"MAX_POST_LENGTH"`
+	BannedWords   []string      `key:"banned_words" env:"BANNED_WORDS"`
+	RestoreWindow time.Duration `key:"restore_window" env:"POST_RESTORE_WINDOW"`
+	PurgeInterval time.Duration `key:"purge_interval" env:"POST_PURGE_INTERVAL"`
+}
+
+type UploadSettings struct {
+	MaxAvatarBytes        int64 `key:"max_avatar_bytes" env:"MAX_AVATAR_BYTES"`
+	MaxAttachmentBytes    int64 `key:"max_attachment_bytes" env:"MAX_ATTACHMENT_BYTES"`
+	MaxAttachmentsPerPost int   `key:"max_attachments_per_post" env:"MAX_ATTACHMENTS_PER_POST"`
+}
+
+// DefaultSettings returns the settings used for anything no source sets.
+func DefaultSettings() Settings {
+	return Settings{
+		Port:     "8080",
+		Platform: "prod",
+		MediaDir: "uploads",
+		DB: DBSettings{
+			MaxOpenConns:       25,
+			MaxIdleConns:       25,
+			ConnMaxLifetime:    30 * time.Minute,
+			ConnMaxIdleTime:    5 * time.Minute,
+			ConnectAttempts:    8,
+			ConnectBackoffBase: 500 * time.Millisecond,
+			ConnectBackoffMax:  10 * time.Second,
+			ConnectTimeout:     5 * time.Second,
+		},
+		HTTP: HTTPSettings{
+			ReadHeaderTimeout:  5 * time.Second,
+			ReadTimeout:        30 * time.Second,
+			WriteTimeout:       30 * time.Second,
+			IdleTimeout:        2 * time.Minute,
+			ShutdownTimeout:    20 * time.Second,
+			HealthCheckTimeout: 2 * time.Second,
+		},
+		JWT: JWTSettings{
+			Issuer:    "chirpy",
+			AccessTTL: time.Hour,
+		},
+		Password: PasswordSettings{
+			Hasher:            "bcrypt",
+			BcryptCost:        10,
+			Argon2MemoryKiB:   64 * 1024,
+			Argon2Iterations:  3,
+			Argon2Parallelism: 2,
+		},
+		Mailer: MailerSettings{
+			Kind: "log",
+		},
+		Auth: AuthSettings{
+			EmailVerificationTTL:  24 * time.Hour,
+			PasswordResetTTL:      time.Hour,
+			TOTPIssuer:            "Chirpy",
+			TwoFactorChallengeTTL: 5 * time.Minute,
+		},
+		Login: LoginSettings{
+			AttemptStore:    "memory",
+			FreeAttempts:    3,
+			LockoutAfter:    10,
+			IPFreeAttempts:  20,
+			IPLockoutAfter:  100,
+			BackoffBase:     time.Second,
+			BackoffMax:      time.Minute,
+			LockoutDuration: 15 * time.Minute,
+			FailureWindow:   15 * time.Minute,
+		},
+		Posts: PostSettings{
+			MaxLength:     140,
+			BannedWords:   []string{"kerfuffle", "sharbert", "fornax"},
+			RestoreWindow: 7 * 24 * time.Hour,
+			PurgeInterval: time.Hour,
+		},
+		Uploads: UploadSettings{
+			MaxAvatarBytes:        2 << 20,
+			MaxAttachmentBytes:    8 << 20,
+			MaxAttachmentsPerPost: 4,
+		},
+	}
+}
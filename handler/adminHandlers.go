@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/Abo-Omar-74/httpServer/helper"
+	"github.com/Abo-Omar-74/httpServer/internal/auth"
+	"github.com/Abo-Omar-74/httpServer/internal/database"
+	"github.com/Abo-Omar-74/httpServer/model"
+	"github.com/google/uuid"
+)
+
+// GrantRoleHandler sets the role of a user. Only admins may call it.
+func (h *Handler) GrantRoleHandler(w http.ResponseWriter , r *http.Request , jwtUserID uuid.UUID){
+	if r.Method != http.MethodPut{
+		helper.RespondWithError(w , http.StatusMethodNotAllowed , "Only PUT requests are allowed.")
+		return
+	}
+
+	type parameters struct{
+		Role string `json:"role"`
+	}
+	var params parameters
+
+	body , err := io.ReadAll(r.Body)
+	if err != nil{
+		helper.RespondWithError(w,http.StatusBadRequest , "Failed to read request body.")
+		return
+	}
+	err = json.Unmarshal(body , &params)
+	if err != nil{
+		helper.RespondWithError(w,http.StatusBadRequest , "Invalid request format.")
+		return
+	}
+	if !auth.ValidRole(params.Role){
+		helper.RespondWithError(w,http.StatusBadRequest , "Role must be one of 'user', 'moderator' or 'admin'.")
+		return
+	}
+
+	h.setUserRole(w , r , jwtUserID , params.Role)
+}
+
+// RevokeRoleHandler drops a user back to the plain user role. Only admins may call it.
+func (h *Handler) RevokeRoleHandler(w http.ResponseWriter , r *http.Request , jwtUserID uuid.UUID){
+	if r.Method != http.MethodDelete{
+		helper.RespondWithError(w , http.StatusMethodNotAllowed , "Only DELETE requests are supported.")
+		return
+	}
+
+	h.setUserRole(w , r , jwtUserID , auth.RoleUser)
+}
+
+func (h *Handler) setUserRole(w http.ResponseWriter , r *http.Request , jwtUserID uuid.UUID , role string){
+	userID , err := uuid.Parse(r.PathValue("userID"))
+	if err != nil{
+		helper.RespondWithError(w,http.StatusBadRequest , "Invalid request parameters.")
+		return
+	}
+	// Admins can't change their own role so the last admin can't lock everyone out.
+	if userID == jwtUserID{
+		helper.RespondWithError(w,http.StatusForbidden , "You are not allowed to change your own role.")
+		return
+	}
+
+	user , err := h.Cfg.Db.SetUserRole(r.Context() , database.SetUserRoleParams{ID: userID , Role: role})
+	if err != nil{
+		if errors.Is(err , sql.ErrNoRows){
+			helper.RespondWithError(w , http.StatusNotFound , "User not found")
+		}else {
+			helper.RespondWithError(w , http.StatusInternalServerError , "Unable to process the request")
+		}
+		return
+	}
+	helper.RespondWithJSON(w , http.StatusOK , model.DatabaseUserToUser(user))
+}
@@ -0,0 +1,212 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/Abo-Omar-74/httpServer/helper"
+	"github.com/Abo-Omar-74/httpServer/internal/database"
+	"github.com/Abo-Omar-74/httpServer/internal/pagination"
+	"github.com/Abo-Omar-74/httpServer/model"
+	"github.com/google/uuid"
+)
+
+// FollowUserHandler makes the user follow another user. Following someone twice is a no-op.
+func (h *Handler) FollowUserHandler(w http.ResponseWriter , r *http.Request , jwtUserID uuid.UUID){
+	if r.Method != http.MethodPut{
+		helper.RespondWithError(w,http.StatusMethodNotAllowed , "Only PUT requests are supported.")
+		return
+	}
+
+	userID , err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		helper.RespondWithError(w,http.StatusBadRequest , "Invalid request parameters.")
+		return 
+	}
+	if userID == jwtUserID{
+		helper.RespondWithError(w,http.StatusBadRequest , "You can't follow yourself.")
+		return
+	}
+
+	_ , err = h.Cfg.Db.FindUserByID(r.Context() , userID)
+	if err != nil{
+		if errors.Is(err , sql.ErrNoRows){
+			helper.RespondWithError(w , http.StatusNotFound , "User not found.")
+		}else {
+			helper.RespondWithError(w, http.StatusInternalServerError, "An unexpected error occurred.")
+		}
+		return
+	}
+
+	err = h.Cfg.Db.FollowUser(r.Context() , database.FollowUserParams{FollowerID: jwtUserID , FolloweeID: userID})
+	if err != nil{
+		helper.RespondWithError(w , http.StatusInternalServerError , "Unable to follow user.")
+		return
+	}
+	helper.RespondWithJSON(w , http.StatusNoContent , nil)
+}
+
+// UnfollowUserHandler stops the user from following another user.
+func (h *Handler) UnfollowUserHandler(w http.ResponseWriter , r *http.Request , jwtUserID uuid.UUID){
+	if r.Method != http.MethodDelete{
+		helper.RespondWithError(w,http.StatusMethodNotAllowed , "Only DELETE requests are supported.")
+		return
+	}
+
+	userID , err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		helper.RespondWithError(w,http.StatusBadRequest , "Invalid request parameters.")
+		return 
+	}
+
+	removed , err := h.Cfg.Db.UnfollowUser(r.Context() , database.UnfollowUserParams{FollowerID: jwtUserID , FolloweeID: userID})
+	if err != nil{
+		helper.RespondWithError(w , http.StatusInternalServerError , "Unable to unfollow user.")
+		return
+	}
+	if removed == 0{
+		helper.RespondWithError(w , http.StatusNotFound , "You are not following this user.")
+		return
+	}
+	helper.RespondWithJSON(w , http.StatusNoContent , nil)
+}
+
+// GetFollowersHandler lists the users following a user, most recent first.
+func (h *Handler) GetFollowersHandler(w http.ResponseWriter , r *http.Request){
+	h.listFollows(w , r , func (params database.ListFollowersParams) ([]database.ListFollowersRow , error){
+		return h.Cfg.Db.ListFollowers(r.Context() , params)
+	})
+}
+
+// GetFollowingHandler lists the users a user follows, most recent first.
+func (h *Handler) GetFollowingHandler(w http.ResponseWriter , r *http.Request){
+	h.listFollows(w , r , func (params database.ListFollowersParams) ([]database.ListFollowersRow , error){
+		rows , err := h.Cfg.Db.ListFollowing(r.Context() , database.ListFollowingParams(params))
+		following := make([]database.ListFollowersRow , 0 , len(rows))
+		for _ , row := range rows{
+			following = append(following , database.ListFollowersRow(row))
+		}
+		return following , err
+	})
+}
+
+// listFollows responds with one page of a follower or following list read by list.
+func (h *Handler) listFollows(w http.ResponseWriter , r *http.Request , list func (database.ListFollowersParams) ([]database.ListFollowersRow , error)){
+	if r.Method != http.MethodGet{
+		helper.RespondWithError(w,http.StatusMethodNotAllowed , "Only GET requests are allowed")
+		return
+	}
+
+	userID , err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		helper.RespondWithError(w,http.StatusBadRequest , "Invalid request parameters.")
+		return 
+	}
+	limit , err := pagination.ParseLimit(r.URL.Query().Get("limit"))
+	if err != nil{
+		helper.RespondWithError(w , http.StatusBadRequest , "Limit must be between 1 and 100.")
+		return
+	}
+	cursorCreatedAt , cursorID , err := cursorParams(r)
+	if err != nil{
+		helper.RespondWithError(w , http.StatusBadRequest , "Invalid cursor.")
+		return
+	}
+
+	_ , err = h.Cfg.Db.FindUserByID(r.Context() , userID)
+	if err != nil{
+		if errors.Is(err , sql.ErrNoRows){
+			helper.RespondWithError(w , http.StatusNotFound , "User not found.")
+		}else {
+			helper.RespondWithError(w, http.StatusInternalServerError, "An unexpected error occurred.")
+		}
+		return
+	}
+
+	// One extra row tells whether another page follows.
+	rows , err := list(database.ListFollowersParams{
+		UserID          : userID,
+		CursorCreatedAt : cursorCreatedAt,
+		CursorID        : cursorID,
+		Limit           : int32(limit + 1),
+	})
+	if err != nil{
+		helper.RespondWithError(w , http.StatusInternalServerError , "Failed to fetch follows.")
+		return
+	}
+
+	page := model.FollowsPage{Follows: []model.Follow{}}
+	if len(rows) > limit{
+		rows = rows[:limit]
+		last := rows[len(rows)-1]
+		page.NextCursor = pagination.Cursor{CreatedAt: last.CreatedAt , ID: last.UserID}.Encode()
+	}
+	for _ , row := range rows{
+		page.Follows = append(page.Follows , model.Follow{UserID: row.UserID , FollowedAt: row.CreatedAt})
+	}
+	helper.RespondWithJSON(w , http.StatusOK , page)
+}
+
+// TimelineHandler retrieves a page of posts by the user and the users they follow, newest first.
+func (h *Handler) TimelineHandler(w http.ResponseWriter , r *http.Request , jwtUserID uuid.UUID){
+	if r.Method != http.MethodGet{
+		helper.RespondWithError(w,http.StatusMethodNotAllowed , "Only GET requests are allowed")
+		return
+	}
+
+	limit , err := pagination.ParseLimit(r.URL.Query().Get("limit"))
+	if err != nil{
+		helper.RespondWithError(w , http.StatusBadRequest , "Limit must be between 1 and 100.")
+		return
+	}
+	cursorCreatedAt , cursorID , err := cursorParams(r)
+	if err != nil{
+		helper.RespondWithError(w , http.StatusBadRequest , "Invalid cursor.")
+		return
+	}
+
+	followeeIDs , err := h.Cfg.Db.ListFolloweeIDs(r.Context() , jwtUserID)
+	if err != nil{
+		helper.RespondWithError(w , http.StatusInternalServerError , "Failed to fetch timeline.")
+		return
+	}
+
+	// One extra row tells whether another page follows.
+	dbPosts , err := h.Cfg.Db.GetPostsByAuthorIDs(r.Context() , database.GetPostsByAuthorIDsParams{
+		AuthorIds       : append(followeeIDs , jwtUserID),
+		CursorCreatedAt : cursorCreatedAt,
+		CursorID        : cursorID,
+		Limit           : int32(limit + 1),
+	})
+	if err != nil{
+		helper.RespondWithError(w , http.StatusInternalServerError , "Failed to fetch timeline.")
+		return
+	}
+
+	page := model.PostsPage{}
+	if len(dbPosts) > limit{
+		dbPosts = dbPosts[:limit]
+		last := dbPosts[len(dbPosts)-1]
+		page.NextCursor = pagination.Cursor{CreatedAt: last.CreatedAt , ID: last.ID}.Encode()
+	}
+	page.Posts = model.DatabasePostsToPosts(dbPosts)
+	if err := h.withPostDetails(r , postPointers(page.Posts)...); err != nil{
+		helper.RespondWithError(w , http.StatusInternalServerError , "Failed to fetch timeline.")
+		return
+	}
+	helper.RespondWithJSON(w , http.StatusOK , page)
+}
+
+// cursorParams decodes the optional "cursor" query parameter into keyset query arguments.
+func cursorParams(r *http.Request) (sql.NullTime , uuid.NullUUID , error){
+	cursorStr := r.URL.Query().Get("cursor")
+	if cursorStr == ""{
+		return sql.NullTime{} , uuid.NullUUID{} , nil
+	}
+	cursor , err := pagination.Decode(cursorStr)
+	if err != nil{
+		return sql.NullTime{} , uuid.NullUUID{} , err
+	}
+	return sql.NullTime{Time: cursor.CreatedAt , Valid: true} , uuid.NullUUID{UUID: cursor.ID , Valid: true} , nil
+}
@@ -0,0 +1,37 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/Abo-Omar-74/httpServer/helper"
+	"github.com/Abo-Omar-74/httpServer/internal/health"
+)
+
+// LivenessHandler reports that the process is up. It checks no dependencies, so a
+// database outage doesn't get the server restarted.
+func (h *Handler) LivenessHandler(w http.ResponseWriter , r *http.Request){
+	if r.Method != http.MethodGet{
+		helper.RespondWithError(w,http.StatusMethodNotAllowed , "Only GET requests are allowed")
+		return
+	}
+
+	w.Header().Set("Cache-Control" , "no-store")
+	helper.RespondWithJSON(w , http.StatusOK , map[string]string{"status" : health.StatusOK})
+}
+
+// ReadinessHandler runs the readiness checks and answers 503 when any of them fails
+// or the server is shutting down.
+func (h *Handler) ReadinessHandler(w http.ResponseWriter , r *http.Request){
+	if r.Method != http.MethodGet{
+		helper.RespondWithError(w,http.StatusMethodNotAllowed , "Only GET requests are allowed")
+		return
+	}
+
+	report := h.Cfg.Health.Run(r.Context())
+	code := http.StatusOK
+	if report.Status != health.StatusOK{
+		code = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Cache-Control" , "no-store")
+	helper.RespondWithJSON(w , code , report)
+}
@@ -0,0 +1,18 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/Abo-Omar-74/httpServer/helper"
+)
+
+// JWKSHandler publishes the public JWT verification keys so other services can validate tokens.
+func (h *Handler) JWKSHandler(w http.ResponseWriter , r *http.Request){
+	if r.Method != http.MethodGet{
+		helper.RespondWithError(w,http.StatusMethodNotAllowed , "Only GET requests are allowed")
+		return
+	}
+
+	w.Header().Set("Cache-Control" , "public, max-age=300")
+	helper.RespondWithJSON(w , http.StatusOK , h.Cfg.Jwt.Keys.JWKS())
+}
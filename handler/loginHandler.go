@@ -1,15 +1,22 @@
 package handler
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"io"
+	"log"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/Abo-Omar-74/httpServer/helper"
 	"github.com/Abo-Omar-74/httpServer/internal/auth"
 	"github.com/Abo-Omar-74/httpServer/internal/database"
+	"github.com/Abo-Omar-74/httpServer/internal/lockout"
 	"github.com/google/uuid"
 )
 
@@ -27,11 +34,13 @@ type LoginResponse struct{
 }
 
 type RefreshResponse struct{
-	RefreshToken string `json:"token"`
+	AccessToken string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
 }
 
 // LoginHandler handles user login by verifying the email and password.
-// If authentication is successful, it returns an authentication token and a refresh token.
+// If authentication is successful, it returns an authentication token and a refresh token,
+// or a two-factor challenge token when the user has enabled TOTP.
 func (h *Handler) LoginHandler(w http.ResponseWriter , r *http.Request){
 	
 	type parameters struct{
@@ -55,37 +64,74 @@ func (h *Handler) LoginHandler(w http.ResponseWriter , r *http.Request){
 		return 
 	}
 
+	if !h.checkLoginLockout(w , r , params.Email){
+		return
+	}
+
 	user , err := h.Cfg.Db.FindUserByEmail(r.Context() , params.Email)
 
 	if err != nil{
+		h.recordLoginFailure(r , params.Email)
 		helper.RespondWithError(w,http.StatusUnauthorized , "Incorrect email or password")
 		return
 	}
 
-	err = auth.CheckPasswordHash(user.HashedPassword , params.Password)
+	needsRehash , err := h.Cfg.PasswordHasher.Verify(user.HashedPassword , params.Password)
 	if err != nil{
+		h.recordLoginFailure(r , params.Email)
 		helper.RespondWithError(w,http.StatusUnauthorized , "Incorrect email or password")
 		return
 	}
+	if needsRehash{
+		h.rehashPassword(r.Context() , user.ID , params.Password)
+	}
+
+	credential , err := h.Cfg.Db.GetTotpCredential(r.Context() , user.ID)
+	if err != nil && !errors.Is(err , sql.ErrNoRows){
+		helper.RespondWithError(w,http.StatusInternalServerError , "An error occurred while processing your request.")
+		return
+	}
+	if err == nil && credential.ConfirmedAt.Valid{
+		h.respondWithTwoFactorChallenge(w , r , user)
+		return
+	}
 
+	h.respondWithLogin(w , r , user)
+}
 
-	token , err:= auth.MakeJWT(user.ID , h.Cfg.JwtSecret)
+// respondWithLogin starts a new session for an authenticated user and responds with its tokens.
+func (h *Handler) respondWithLogin(w http.ResponseWriter , r *http.Request , user database.User){
+	token , err:= h.Cfg.Jwt.MakeJWT(user.ID , userClaims(user))
 
 	if err != nil{
 		helper.RespondWithError(w,http.StatusInternalServerError , "An error occurred while processing your request.")
 		return
 	}
-	refreshToken , err := auth.MakeRefreshToken()
+	// Every login starts a new session; its ID is the family of all refresh tokens rotated from it.
+	session , err := h.Cfg.Db.CreateSession(r.Context() , database.CreateSessionParams{
+		UserID    : user.ID,
+		UserAgent : r.UserAgent(),
+		IpAddress : helper.ClientIP(r),
+	})
+	if err != nil{
+		helper.RespondWithError(w , http.StatusInternalServerError , "An error occurred while processing your request.")
+		return
+	}
+	refreshToken , err := h.issueRefreshToken(r.Context() , user.ID , session.ID)
 
 	if err != nil{
 		helper.RespondWithError(w , http.StatusInternalServerError , "An error occurred while processing your request.")
 		return
 	}
-	res := LoginResponse{user.ID , user.CreatedAt , user.UpdatedAt , user.Email , token , refreshToken , user.IsPremium}
+	// Failures are only forgotten once every factor has passed, so a known password
+	// doesn't reset the count while second-factor codes are being guessed.
+	h.clearLoginFailures(r , user.Email)
+	res := LoginResponse{user.ID , user.CreatedAt , user.UpdatedAt , user.Email , token , refreshToken.Token , user.IsPremium}
 	helper.RespondWithJSON(w,http.StatusOK,res)
 }
 
-// RefreshHandler handles the refreshing of JWT tokens using a valid refresh token.
+// RefreshHandler exchanges a valid refresh token for a new access token and a new refresh token.
+// The presented refresh token is revoked; presenting it again revokes its whole token family.
 func (h *Handler) RefreshHandler(w http.ResponseWriter , r *http.Request){
 	if r.Method != http.MethodPost{
 		helper.RespondWithError(w,http.StatusMethodNotAllowed , "Only POST requests are supported.")
@@ -103,18 +149,61 @@ func (h *Handler) RefreshHandler(w http.ResponseWriter , r *http.Request){
 		helper.RespondWithError(w , http.StatusUnauthorized , "Invalid refresh token.")
 		return
 	}
-	if dbRefreshToken.ExpiresAt.Before(time.Now()) || dbRefreshToken.RevokedAt.Valid{
+	if dbRefreshToken.RevokedAt.Valid{
+		// A revoked token being presented again means it was copied; kill the whole family.
+		h.revokeTokenFamily(r , dbRefreshToken)
+		helper.RespondWithError(w , http.StatusUnauthorized , "Refresh token is no longer valid.")
+		return
+	}
+	if dbRefreshToken.ExpiresAt.Before(time.Now()){
 		helper.RespondWithError(w , http.StatusUnauthorized , "Refresh token is no longer valid.")
 		return
 	} 
-	jwtToken , err := auth.MakeJWT(dbRefreshToken.UserID , h.Cfg.JwtSecret)
+
+	newRefreshToken , err := h.issueRefreshToken(r.Context() , dbRefreshToken.UserID , dbRefreshToken.FamilyID)
+	if err != nil{
+		helper.RespondWithError(w , http.StatusInternalServerError , "Failed to generate token.")
+		return
+	}
+
+	rotated , err := h.Cfg.Db.RotateRefreshToken(r.Context() , database.RotateRefreshTokenParams{
+		Token      : dbRefreshToken.Token,
+		ReplacedBy : sql.NullString{String: newRefreshToken.Token , Valid: true},
+	})
+	if err != nil{
+		helper.RespondWithError(w , http.StatusInternalServerError , "Failed to generate token.")
+		return
+	}
+	if rotated == 0{
+		// Another request rotated this token first, which is the same as reuse.
+		h.revokeTokenFamily(r , dbRefreshToken)
+		helper.RespondWithError(w , http.StatusUnauthorized , "Refresh token is no longer valid.")
+		return
+	}
+
+	err = h.Cfg.Db.TouchSession(r.Context() , database.TouchSessionParams{
+		ID        : dbRefreshToken.FamilyID,
+		UserAgent : r.UserAgent(),
+		IpAddress : helper.ClientIP(r),
+	})
+	if err != nil{
+		log.Printf("Failed to update session %s: %v", dbRefreshToken.FamilyID , err)
+	}
+
+	user , err := h.Cfg.Db.FindUserByID(r.Context() , dbRefreshToken.UserID)
+	if err != nil{
+		helper.RespondWithError(w , http.StatusUnauthorized , "Invalid refresh token.")
+		return
+	}
+
+	jwtToken , err := h.Cfg.Jwt.MakeJWT(user.ID , userClaims(user))
 	
 	if err != nil{
 		helper.RespondWithError(w , http.StatusInternalServerError , "Failed to generate token.")
 		return
 	}
 
-	helper.RespondWithJSON(w,http.StatusOK , RefreshResponse{jwtToken})
+	helper.RespondWithJSON(w,http.StatusOK , RefreshResponse{jwtToken , newRefreshToken.Token})
 }
 
 // RevokeHandler revokes a user's refresh token, making it invalid for future use.
@@ -152,5 +241,109 @@ func (h *Handler) RevokeHandler(w http.ResponseWriter , r *http.Request){
 		return
 	}
 
+	// Logging out ends the session the token belongs to.
+	err = h.Cfg.Db.RevokeSession(r.Context() , refreshToken.FamilyID)
+
+	if err != nil{
+		helper.RespondWithError(w, http.StatusInternalServerError, "Failed to revoke refresh token.")
+		return
+	}
+
 	helper.RespondWithJSON(w,http.StatusNoContent , nil)
-}
\ No newline at end of file
+}
+
+// issueRefreshToken creates a refresh token for the user and stores it in the given token family.
+func (h *Handler) issueRefreshToken(ctx context.Context , userID uuid.UUID , familyID uuid.UUID) (database.RefreshToken , error){
+	token , err := auth.MakeRefreshToken()
+	if err != nil{
+		return database.RefreshToken{} , err
+	}
+	return h.Cfg.Db.GenerateRefreshToken(ctx , database.GenerateRefreshTokenParams{
+		Token    : token,
+		UserID   : userID,
+		FamilyID : familyID,
+	})
+}
+
+// revokeTokenFamily revokes the session of the given token and every refresh token in its family.
+func (h *Handler) revokeTokenFamily(r *http.Request , token database.RefreshToken){
+	log.Printf("Refresh token reuse detected for user %s, revoking family %s", token.UserID , token.FamilyID)
+	err := h.revokeSession(r.Context() , token.FamilyID)
+	if err != nil{
+		log.Printf("Failed to revoke refresh token family %s: %v", token.FamilyID , err)
+	}
+}
+
+// rehashPassword replaces a user's stored hash with one from the default hasher.
+// Failures are only logged since the user has already been authenticated.
+func (h *Handler) rehashPassword(ctx context.Context , userID uuid.UUID , password string){
+	hash , err := h.Cfg.PasswordHasher.Hash(password)
+	if err != nil{
+		log.Printf("Failed to rehash password for user %s: %v", userID , err)
+		return
+	}
+	err = h.Cfg.Db.UpdateUserPassword(ctx , database.UpdateUserPasswordParams{ID: userID , HashedPassword: hash})
+	if err != nil{
+		log.Printf("Failed to store rehashed password for user %s: %v", userID , err)
+	}
+}
+
+// userClaims collects the claims about the user that are embedded in access tokens.
+func userClaims(user database.User) auth.UserClaims{
+	return auth.UserClaims{
+		IsPremium     : user.IsPremium,
+		EmailVerified : user.EmailVerifiedAt.Valid,
+		Roles         : []string{user.Role},
+	}
+}
+
+// checkLoginLockout responds with 429 and returns false while the email or the client IP is blocked
+// after too many failed logins. Lockout store errors are logged and let the attempt through.
+func (h *Handler) checkLoginLockout(w http.ResponseWriter , r *http.Request , email string) bool{
+	var wait time.Duration
+	for _ , check := range []struct{ guard *lockout.Guard ; key string }{
+		{h.Cfg.LoginEmailGuard , normalizeEmail(email)},
+		{h.Cfg.LoginIPGuard , helper.ClientIP(r)},
+	}{
+		retryAfter , err := check.guard.Check(r.Context() , check.key)
+		if err != nil{
+			log.Printf("Failed to check login lockout: %v", err)
+			continue
+		}
+		if retryAfter > wait{
+			wait = retryAfter
+		}
+	}
+	if wait <= 0{
+		return true
+	}
+
+	w.Header().Set("Retry-After" , strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	helper.RespondWithError(w , http.StatusTooManyRequests , "Too many failed login attempts. Please try again later.")
+	return false
+}
+
+// recordLoginFailure counts a failed login against both the email and the client IP.
+func (h *Handler) recordLoginFailure(r *http.Request , email string){
+	_ , err := h.Cfg.LoginEmailGuard.Fail(r.Context() , normalizeEmail(email))
+	if err != nil{
+		log.Printf("Failed to record login failure: %v", err)
+	}
+	_ , err = h.Cfg.LoginIPGuard.Fail(r.Context() , helper.ClientIP(r))
+	if err != nil{
+		log.Printf("Failed to record login failure: %v", err)
+	}
+}
+
+// clearLoginFailures forgets the failures of an email after a complete login.
+// The IP count is left to expire so one valid account can't be used to keep guessing others.
+func (h *Handler) clearLoginFailures(r *http.Request , email string){
+	err := h.Cfg.LoginEmailGuard.Succeed(r.Context() , normalizeEmail(email))
+	if err != nil{
+		log.Printf("Failed to clear login failures: %v", err)
+	}
+}
+
+func normalizeEmail(email string) string{
+	return strings.ToLower(strings.TrimSpace(email))
+}
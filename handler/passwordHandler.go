@@ -0,0 +1,153 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+
+	"github.com/Abo-Omar-74/httpServer/helper"
+	"github.com/Abo-Omar-74/httpServer/internal/auth"
+	"github.com/Abo-Omar-74/httpServer/internal/database"
+	"github.com/Abo-Omar-74/httpServer/internal/mailer"
+)
+
+// ForgotPasswordHandler emails a password reset token to the given address.
+// It answers the same way whether or not the email belongs to a user so accounts can't be enumerated.
+func (h *Handler) ForgotPasswordHandler(w http.ResponseWriter , r *http.Request){
+	if r.Method != http.MethodPost{
+		helper.RespondWithError(w,http.StatusMethodNotAllowed , "Only POST requests are supported.")
+		return
+	}
+
+	type parameters struct{
+		Email string `json:"email"`
+	}
+	var params parameters
+
+	body , err := io.ReadAll(r.Body)
+	if err != nil{
+		helper.RespondWithError(w,http.StatusBadRequest , "Failed to read request body.")
+		return
+	}
+	err = json.Unmarshal(body , &params)
+	if err != nil || params.Email == ""{
+		helper.RespondWithError(w,http.StatusBadRequest , "Invalid request format.")
+		return
+	}
+
+	helper.RespondWithJSON(w , http.StatusAccepted , map[string]string{"status":"If the account exists, a reset email has been sent."})
+
+	// The lookup and the email happen after responding so the response time is the same either way.
+	ctx := context.WithoutCancel(r.Context())
+	h.Cfg.Background.Add(1)
+	go func(){
+		defer h.Cfg.Background.Done()
+		h.sendPasswordResetEmail(ctx , params.Email)
+	}()
+}
+
+// sendPasswordResetEmail creates a reset token for the user with the email, if there is one, and mails it.
+// It runs after the response has been sent, so failures are only logged.
+func (h *Handler) sendPasswordResetEmail(ctx context.Context , email string){
+	user , err := h.Cfg.Db.FindUserByEmail(ctx , email)
+	if err != nil{
+		if !errors.Is(err , sql.ErrNoRows){
+			log.Printf("Failed to look up user for password reset: %v" , err)
+		}
+		return
+	}
+
+	token , err := auth.MakeRefreshToken()
+	if err != nil{
+		log.Printf("Failed to create password reset token for user %s: %v" , user.ID , err)
+		return
+	}
+	// The expiry is set by the database, whose clock the consume query compares it with.
+	_ , err = h.Cfg.Db.CreatePasswordResetToken(ctx , database.CreatePasswordResetTokenParams{
+		TokenHash  : auth.HashToken(token),
+		UserID     : user.ID,
+		TtlSeconds : h.Cfg.PasswordResetTTL.Seconds(),
+	})
+	if err != nil{
+		log.Printf("Failed to store password reset token for user %s: %v" , user.ID , err)
+		return
+	}
+
+	link := fmt.Sprintf("%s/reset-password?token=%s" , h.Cfg.BaseURL , url.QueryEscape(token))
+	err = h.Cfg.Mailer.Send(ctx , mailer.Message{
+		To      : user.Email,
+		Subject : "Reset your password",
+		Body    : fmt.Sprintf("Open this link to choose a new password:\n\n%s\n\nOr send this token to POST /api/password/reset:\n\n%s\n\nThe link expires in %s. If you didn't ask for a reset you can ignore this email." , link , token , h.Cfg.PasswordResetTTL),
+	})
+	if err != nil{
+		log.Printf("Failed to send password reset email to user %s: %v", user.ID , err)
+	}
+}
+
+// ResetPasswordHandler sets a new password using a reset token and logs the user out everywhere.
+func (h *Handler) ResetPasswordHandler(w http.ResponseWriter , r *http.Request){
+	if r.Method != http.MethodPost{
+		helper.RespondWithError(w,http.StatusMethodNotAllowed , "Only POST requests are supported.")
+		return
+	}
+
+	type parameters struct{
+		Token string `json:"token"`
+		Password string `json:"password"`
+	}
+	var params parameters
+
+	body , err := io.ReadAll(r.Body)
+	if err != nil{
+		helper.RespondWithError(w,http.StatusBadRequest , "Failed to read request body.")
+		return
+	}
+	err = json.Unmarshal(body , &params)
+	if err != nil || params.Token == ""{
+		helper.RespondWithError(w,http.StatusBadRequest , "Invalid request format.")
+		return
+	}
+	if params.Password == ""{
+		helper.RespondWithError(w,http.StatusBadRequest , "Password is required.")
+		return
+	}
+
+	token , err := h.Cfg.Db.UsePasswordResetToken(r.Context() , auth.HashToken(params.Token))
+	if err != nil{
+		if errors.Is(err , sql.ErrNoRows){
+			helper.RespondWithError(w , http.StatusBadRequest , "Reset token is invalid or has expired.")
+		}else {
+			helper.RespondWithError(w , http.StatusInternalServerError , "An error occurred while processing your request.")
+		}
+		return
+	}
+
+	hash , err := h.Cfg.PasswordHasher.Hash(params.Password)
+	if err != nil{
+		helper.RespondWithError(w,http.StatusInternalServerError , "An error occurred while processing your request.")
+		return
+	}
+	err = h.Cfg.Db.UpdateUserPassword(r.Context() , database.UpdateUserPasswordParams{ID: token.UserID , HashedPassword: hash})
+	if err != nil{
+		helper.RespondWithError(w,http.StatusInternalServerError , "An error occurred while processing your request.")
+		return
+	}
+
+	err = h.Cfg.Db.InvalidatePasswordResetTokensByUserID(r.Context() , token.UserID)
+	if err != nil{
+		log.Printf("Failed to invalidate password reset tokens for user %s: %v", token.UserID , err)
+	}
+	err = h.revokeAllSessions(r.Context() , token.UserID)
+	if err != nil{
+		helper.RespondWithError(w,http.StatusInternalServerError , "An error occurred while processing your request.")
+		return
+	}
+
+	helper.RespondWithJSON(w , http.StatusNoContent , nil)
+}
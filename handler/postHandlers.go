@@ -7,27 +7,22 @@ import (
 	"io"
 	"net/http"
 	"strings"
-	"time"
 
 	"github.com/Abo-Omar-74/httpServer/helper"
+	"github.com/Abo-Omar-74/httpServer/internal/auth"
+	"github.com/Abo-Omar-74/httpServer/internal/content"
 	"github.com/Abo-Omar-74/httpServer/internal/database"
+	"github.com/Abo-Omar-74/httpServer/internal/pagination"
 	"github.com/Abo-Omar-74/httpServer/model"
 	"github.com/google/uuid"
 )
 
-type postResponse struct{
-	ID        uuid.UUID `json:"id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-  Body string `json:"body"`
-	UserId uuid.UUID `json:"user_id"`
-}
-
-// PostHandler creates a new post for the user.
+// PostHandler creates a new post for the authenticated user, or a reply when parent_id is set.
+// The body is cleaned and validated by the post content pipeline.
 func (h *Handler)PostHandler(w http.ResponseWriter , r *http.Request , jwtUserID uuid.UUID){
 	type parameters struct{
 		Body string `json:"body"`
-		UserID uuid.UUID `json:"user_id"`
+		ParentID uuid.NullUUID `json:"parent_id"`
 	}
 	var params parameters
   
@@ -52,14 +47,25 @@ func (h *Handler)PostHandler(w http.ResponseWriter , r *http.Request , jwtUserID
   // decoder := json.NewDecoder(r.Body)
   // err := decoder.Decode(&post)
 
-	_ , err = h.Cfg.Db.FindUserByID(r.Context() , params.UserID)
+	if params.ParentID.Valid{
+		_ , err = h.Cfg.Db.GetPost(r.Context() , params.ParentID.UUID)
+		if err != nil{
+			if errors.Is(err , sql.ErrNoRows){
+				helper.RespondWithError(w,http.StatusNotFound , "Parent post not found.")
+			}else {
+				helper.RespondWithError(w, http.StatusInternalServerError, "An unexpected error occurred.")
+			}
+			return
+		}
+	}
 
-	if err != nil {
-		helper.RespondWithError(w,http.StatusNotFound , "User not found")
+	cleanBody , err := h.Cfg.PostContent.Process(params.Body)
+	if err != nil{
+		respondWithContentError(w , err)
 		return
 	}
 
-	dbPost , err := h.Cfg.Db.CreatePost(r.Context() , database.CreatePostParams{Body: params.Body , UserID: params.UserID})
+	dbPost , err := h.Cfg.Db.CreatePost(r.Context() , database.CreatePostParams{Body: cleanBody , UserID: jwtUserID , ParentID: params.ParentID})
 
 	if err != nil {
 		helper.RespondWithError(w , http.StatusInternalServerError , "Failed to create post")
@@ -69,7 +75,8 @@ func (h *Handler)PostHandler(w http.ResponseWriter , r *http.Request , jwtUserID
 }
 
 
-// GetPostsHandler retrieves posts, optionally filtered by author ID.
+// GetPostsHandler retrieves a page of posts, optionally filtered by author ID.
+// Pages are ordered by (created_at, id) and continue from the opaque cursor of the previous page.
 func (h *Handler) GetPostsHandler(w http.ResponseWriter , r *http.Request){
 	if r.Method != http.MethodGet{
 		helper.RespondWithError(w,http.StatusMethodNotAllowed , "Only GET requests are allowed")
@@ -88,33 +95,73 @@ func (h *Handler) GetPostsHandler(w http.ResponseWriter , r *http.Request){
 		return
 	}
 
-	var dbPosts []database.Post
-	var err error
-
+	limit , err := pagination.ParseLimit(r.URL.Query().Get("limit"))
+	if err != nil{
+		helper.RespondWithError(w , http.StatusBadRequest , "Limit must be between 1 and 100.")
+		return
+	}
 
-	if authorIdStr == ""{
-		dbPosts , err = h.Cfg.Db.GetAllPosts(r.Context())
-	}else {
-		var authorId uuid.UUID
-		authorId , err = uuid.Parse(authorIdStr)
+	var authorID uuid.NullUUID
+	if authorIdStr != ""{
+		authorID.UUID , err = uuid.Parse(authorIdStr)
 		if err != nil{
 			helper.RespondWithError(w , http.StatusBadRequest , "Invalid request parameters.")
 			return
 		}
-		dbPosts , err = h.Cfg.Db.GetPostsByAuthorID(r.Context() , authorId)
+		authorID.Valid = true
+	}
+
+	var cursorCreatedAt sql.NullTime
+	var cursorID uuid.NullUUID
+	if cursorStr := r.URL.Query().Get("cursor"); cursorStr != ""{
+		cursor , err := pagination.Decode(cursorStr)
+		if err != nil{
+			helper.RespondWithError(w , http.StatusBadRequest , "Invalid cursor.")
+			return
+		}
+		cursorCreatedAt = sql.NullTime{Time: cursor.CreatedAt , Valid: true}
+		cursorID = uuid.NullUUID{UUID: cursor.ID , Valid: true}
+	}
+
+	// One extra row tells whether another page follows.
+	var dbPosts []database.Post
+	if sortParam == "DESC"{
+		dbPosts , err = h.Cfg.Db.ListPostsDesc(r.Context() , database.ListPostsDescParams{
+			AuthorID        : authorID,
+			CursorCreatedAt : cursorCreatedAt,
+			CursorID        : cursorID,
+			Limit           : int32(limit + 1),
+		})
+	}else {
+		dbPosts , err = h.Cfg.Db.ListPostsAsc(r.Context() , database.ListPostsAscParams{
+			AuthorID        : authorID,
+			CursorCreatedAt : cursorCreatedAt,
+			CursorID        : cursorID,
+			Limit           : int32(limit + 1),
+		})
 	}
 	if err != nil{
 		helper.RespondWithError(w , http.StatusInternalServerError , "Failed to fetch posts.")
 		return
 	}
+
+	page := model.PostsPage{}
+	if len(dbPosts) > limit{
+		dbPosts = dbPosts[:limit]
+		last := dbPosts[len(dbPosts)-1]
+		page.NextCursor = pagination.Cursor{CreatedAt: last.CreatedAt , ID: last.ID}.Encode()
+	}
+	page.Posts = model.DatabasePostsToPosts(dbPosts)
+	if err := h.withPostDetails(r , postPointers(page.Posts)...); err != nil{
+		helper.RespondWithError(w , http.StatusInternalServerError , "Failed to fetch reactions.")
+		return
+	}
 	
-	posts := model.DatabasePostsToPosts(dbPosts , sortParam)
-	
-	helper.RespondWithJSON(w,http.StatusOK , posts)
+	helper.RespondWithJSON(w,http.StatusOK , page)
 }
 
 
-// GetPostByIDHandler retrieves a post by its ID from the database.
+// GetPostByIDHandler retrieves a post by its ID from the database. A deleted post that has replies comes back as a tombstone.
 func (h *Handler) GetPostByIDHandler(w http.ResponseWriter , r *http.Request){
 
 	if r.Method != http.MethodGet{
@@ -134,7 +181,7 @@ func (h *Handler) GetPostByIDHandler(w http.ResponseWriter , r *http.Request){
 		return 
 	}
 
-	post , err := h.Cfg.Db.GetPost(r.Context() , id)
+	post , err := h.getPostOrTombstone(r , id)
 	if err != nil{
 		if errors.Is(err , sql.ErrNoRows){
 			helper.RespondWithError(w , http.StatusNotFound , "Post not found.")
@@ -144,16 +191,11 @@ func (h *Handler) GetPostByIDHandler(w http.ResponseWriter , r *http.Request){
 		return
   }
 
-	helper.RespondWithJSON(w,http.StatusOK , postResponse{
-		post.ID , 
-		post.CreatedAt , 
-		post.UpdatedAt, 
-		post.Body,
-		post.UserID,
-	})
+	h.respondWithPost(w , r , post)
 }
 
-// DeletePostHandler deletes a post by ID, ensuring the user is authorized.
+// DeletePostHandler deletes a post by ID. Only the author or a user allowed to delete any post may do so.
+// The post is hidden right away and its author can restore it until the restore window runs out.
 func (h *Handler) DeletePostHandler(w http.ResponseWriter , r *http.Request , jwtUserID uuid.UUID){
 	
 	if r.Method != http.MethodDelete{
@@ -176,14 +218,208 @@ func (h *Handler) DeletePostHandler(w http.ResponseWriter , r *http.Request , jw
 		helper.RespondWithError(w,http.StatusNotFound , "Post not found.")
 		return
   }
-	if jwtUserID != post.UserID{
+	claims , _ := auth.ClaimsFromContext(r.Context())
+	canDeleteAny := claims != nil && auth.HasPermission(claims.Roles , auth.PermissionDeleteAnyPost)
+	if jwtUserID != post.UserID && !canDeleteAny{
 		helper.RespondWithError(w,http.StatusForbidden ,"You are not allowed to delete this post.")
 		return
 	}
-	_ , err = h.Cfg.Db.DeletePost(r.Context() , post.ID)
+	_ , err = h.Cfg.Db.DeletePost(r.Context() , database.DeletePostParams{
+		ID        : post.ID,
+		DeletedBy : uuid.NullUUID{UUID: jwtUserID , Valid: true},
+	})
 	if err != nil{
 		helper.RespondWithError(w , http.StatusInternalServerError , "Unable to delete post.")
 		return 
 	}
 	helper.RespondWithJSON(w,http.StatusNoContent , nil)
 }
+
+// RestorePostHandler brings back a deleted post. Only the author may restore it, within the restore window,
+// and only if they deleted it themselves; posts removed by a moderator stay deleted.
+func (h *Handler) RestorePostHandler(w http.ResponseWriter , r *http.Request , jwtUserID uuid.UUID){
+	if r.Method != http.MethodPost{
+		helper.RespondWithError(w,http.StatusMethodNotAllowed , "Only POST requests are supported.")
+		return
+	}
+
+	id , err := uuid.Parse(r.PathValue("postID"))
+	if err != nil {
+		helper.RespondWithError(w,http.StatusBadRequest , "Invalid request parameters.")
+		return 
+	}
+
+	post , err := h.Cfg.Db.GetDeletedPost(r.Context() , id)
+	if err != nil{
+		if errors.Is(err , sql.ErrNoRows){
+			helper.RespondWithError(w , http.StatusNotFound , "Deleted post not found.")
+		}else {
+			helper.RespondWithError(w, http.StatusInternalServerError, "An unexpected error occurred.")
+		}
+		return
+	}
+	if jwtUserID != post.UserID{
+		helper.RespondWithError(w,http.StatusForbidden ,"You are not allowed to restore this post.")
+		return
+	}
+	if post.DeletedBy.Valid && post.DeletedBy.UUID != post.UserID{
+		helper.RespondWithError(w,http.StatusForbidden ,"This post was removed by a moderator and can't be restored.")
+		return
+	}
+
+	// The window is measured on the database clock, the same one that set deleted_at.
+	post , err = h.Cfg.Db.RestorePost(r.Context() , database.RestorePostParams{
+		ID                   : post.ID,
+		RestoreWindowSeconds : h.Cfg.PostRestoreWindow.Seconds(),
+	})
+	if err != nil{
+		if errors.Is(err , sql.ErrNoRows){
+			helper.RespondWithError(w , http.StatusGone , "The restore window for this post has passed.")
+		}else {
+			helper.RespondWithError(w , http.StatusInternalServerError , "Unable to restore post.")
+		}
+		return
+	}
+	h.respondWithPost(w , r , post)
+}
+
+// EditPostHandler replaces the body of a post. Only the author may edit, and the previous body is kept as a revision.
+func (h *Handler) EditPostHandler(w http.ResponseWriter , r *http.Request , jwtUserID uuid.UUID){
+	if r.Method != http.MethodPut && r.Method != http.MethodPatch{
+		helper.RespondWithError(w,http.StatusMethodNotAllowed , "Only PUT and PATCH requests are supported.")
+		return
+	}
+
+	type parameters struct{
+		Body string `json:"body"`
+	}
+	var params parameters
+
+	id , err := uuid.Parse(r.PathValue("postID"))
+	if err != nil {
+		helper.RespondWithError(w,http.StatusBadRequest , "Invalid request parameters.")
+		return 
+	}
+
+	body , err := io.ReadAll(r.Body)
+	if err != nil{
+		helper.RespondWithError(w,http.StatusBadRequest , "Failed to read request body")
+		return
+	}
+	err = json.Unmarshal(body , &params)
+	if err != nil{
+		helper.RespondWithError(w,http.StatusBadRequest , "Invalid JSON payload")
+		return
+	}
+
+	post, err := h.Cfg.Db.GetPost(r.Context() , id)
+	if err != nil{
+		helper.RespondWithError(w,http.StatusNotFound , "Post not found.")
+		return
+	}
+	if jwtUserID != post.UserID{
+		helper.RespondWithError(w,http.StatusForbidden ,"You are not allowed to edit this post.")
+		return
+	}
+
+	cleanBody , err := h.Cfg.PostContent.Process(params.Body)
+	if err != nil{
+		respondWithContentError(w , err)
+		return
+	}
+	if post.Body == cleanBody{
+		h.respondWithPost(w , r , post)
+		return
+	}
+
+	post , err = h.Cfg.Db.EditPost(r.Context() , database.EditPostParams{ID: post.ID , Body: cleanBody})
+	if err != nil{
+		helper.RespondWithError(w , http.StatusInternalServerError , "Unable to edit post.")
+		return
+	}
+	h.respondWithPost(w , r , post)
+}
+
+// GetPostRevisionsHandler lists the previous bodies of a post, newest first.
+func (h *Handler) GetPostRevisionsHandler(w http.ResponseWriter , r *http.Request){
+	if r.Method != http.MethodGet{
+		helper.RespondWithError(w,http.StatusMethodNotAllowed , "Only GET requests are allowed")
+		return
+	}
+
+	id , err := uuid.Parse(r.PathValue("postID"))
+	if err != nil {
+		helper.RespondWithError(w,http.StatusBadRequest , "Invalid request parameters.")
+		return 
+	}
+
+	_ , err = h.Cfg.Db.GetPost(r.Context() , id)
+	if err != nil{
+		if errors.Is(err , sql.ErrNoRows){
+			helper.RespondWithError(w , http.StatusNotFound , "Post not found.")
+		}else {
+			helper.RespondWithError(w, http.StatusInternalServerError, "An unexpected error occurred.")
+		}
+		return
+	}
+
+	dbRevisions , err := h.Cfg.Db.GetPostRevisions(r.Context() , id)
+	if err != nil{
+		helper.RespondWithError(w , http.StatusInternalServerError , "Failed to fetch revisions.")
+		return
+	}
+	revisions := []model.PostRevision{}
+	for _ , revision := range dbRevisions{
+		revisions = append(revisions , model.DatabasePostRevisionToPostRevision(revision))
+	}
+	helper.RespondWithJSON(w , http.StatusOK , revisions)
+}
+
+// withPostDetails fills in the attachments, reactions and reply counts of the posts.
+func (h *Handler) withPostDetails(r *http.Request , posts ...*model.Post) error{
+	if err := h.withAttachments(r , posts...); err != nil{
+		return err
+	}
+	if err := h.withReactions(r , posts...); err != nil{
+		return err
+	}
+	return h.withReplyCounts(r , posts...)
+}
+
+// respondWithPost writes a single post along with its attachments, reactions and reply count.
+func (h *Handler) respondWithPost(w http.ResponseWriter , r *http.Request , dbPost database.Post){
+	post := model.DatabasePostToPost(dbPost)
+	if err := h.withPostDetails(r , &post); err != nil{
+		helper.RespondWithError(w , http.StatusInternalServerError , "Failed to fetch reactions.")
+		return
+	}
+	helper.RespondWithJSON(w , http.StatusOK , post)
+}
+
+// postPointers returns pointers into posts so their fields can be filled in place.
+func postPointers(posts []model.Post) []*model.Post{
+	pointers := make([]*model.Post , len(posts))
+	for i := range posts{
+		pointers[i] = &posts[i]
+	}
+	return pointers
+}
+
+// respondWithContentError reports why a body was rejected by the content pipeline.
+func respondWithContentError(w http.ResponseWriter , err error){
+	respondWithValidationError(w , "Post body is invalid." , err)
+}
+
+// respondWithValidationError lists the violations of a *content.ValidationError under message.
+// Any other error is reported as an internal error.
+func respondWithValidationError(w http.ResponseWriter , message string , err error){
+	var validationErr *content.ValidationError
+	if !errors.As(err , &validationErr){
+		helper.RespondWithError(w , http.StatusInternalServerError , "Failed to process the request.")
+		return
+	}
+	helper.RespondWithJSON(w , http.StatusBadRequest , struct{
+		Error string `json:"error"`
+		Violations []content.Violation `json:"violations"`
+	}{message , validationErr.Violations})
+}
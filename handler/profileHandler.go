@@ -0,0 +1,147 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/Abo-Omar-74/httpServer/helper"
+	"github.com/Abo-Omar-74/httpServer/internal/database"
+	"github.com/Abo-Omar-74/httpServer/internal/profile"
+	"github.com/Abo-Omar-74/httpServer/model"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// GetUserProfileHandler returns the public profile of a user by ID.
+func (h *Handler) GetUserProfileHandler(w http.ResponseWriter , r *http.Request){
+	if r.Method != http.MethodGet{
+		helper.RespondWithError(w,http.StatusMethodNotAllowed , "Only GET requests are allowed")
+		return
+	}
+
+	userID , err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		helper.RespondWithError(w,http.StatusBadRequest , "Invalid request parameters.")
+		return 
+	}
+
+	user , err := h.Cfg.Db.FindUserByID(r.Context() , userID)
+	respondWithProfile(w , user , err)
+}
+
+// GetUserByHandleHandler returns the public profile of the user named by the "handle" query parameter.
+// Handles match case-insensitively and may start with "@".
+func (h *Handler) GetUserByHandleHandler(w http.ResponseWriter , r *http.Request){
+	if r.Method != http.MethodGet{
+		helper.RespondWithError(w,http.StatusMethodNotAllowed , "Only GET requests are allowed")
+		return
+	}
+
+	handle := profile.NormalizeHandle(r.URL.Query().Get("handle"))
+	if handle == ""{
+		helper.RespondWithError(w,http.StatusBadRequest , "The 'handle' query parameter is required.")
+		return
+	}
+
+	user , err := h.Cfg.Db.FindUserByHandle(r.Context() , sql.NullString{String: handle , Valid: true})
+	respondWithProfile(w , user , err)
+}
+
+// UpdateProfileHandler changes the user's profile. Fields left out of the request keep their
+// current value, and an empty string clears a field.
+func (h *Handler) UpdateProfileHandler(w http.ResponseWriter , r *http.Request , jwtUserID uuid.UUID){
+	if r.Method != http.MethodPut && r.Method != http.MethodPatch{
+		helper.RespondWithError(w,http.StatusMethodNotAllowed , "Only PUT and PATCH requests are supported.")
+		return
+	}
+
+	type parameters struct{
+		Handle      *string `json:"handle"`
+		DisplayName *string `json:"display_name"`
+		Bio         *string `json:"bio"`
+		AvatarURL   *string `json:"avatar_url"`
+	}
+	var params parameters
+
+	body , err := io.ReadAll(r.Body)
+	if err != nil{
+		helper.RespondWithError(w,http.StatusBadRequest , "Failed to read request body.")
+		return 
+	}
+	err = json.Unmarshal(body , &params)
+	if err != nil{
+		helper.RespondWithError(w,http.StatusBadRequest , "Invalid request format.")
+		return 
+	}
+
+	user , err := h.Cfg.Db.FindUserByID(r.Context() , jwtUserID)
+	if err != nil{
+		helper.RespondWithError(w,http.StatusUnauthorized , "Unauthorized")
+		return
+	}
+
+	fields := profile.Fields{
+		Handle      : user.Handle.String,
+		DisplayName : user.DisplayName,
+		Bio         : user.Bio,
+		AvatarURL   : user.AvatarUrl,
+	}
+	if params.Handle != nil{
+		fields.Handle = *params.Handle
+	}
+	if params.DisplayName != nil{
+		fields.DisplayName = *params.DisplayName
+	}
+	if params.Bio != nil{
+		fields.Bio = *params.Bio
+	}
+	if params.AvatarURL != nil{
+		fields.AvatarURL = *params.AvatarURL
+	}
+
+	fields , err = fields.Clean()
+	if err != nil{
+		respondWithValidationError(w , "Profile is invalid." , err)
+		return
+	}
+
+	// The UNIQUE constraint on handle settles races between users claiming the same handle.
+	user , err = h.Cfg.Db.UpdateUserProfile(r.Context() , database.UpdateUserProfileParams{
+		ID          : user.ID,
+		Handle      : sql.NullString{String: fields.Handle , Valid: fields.Handle != ""},
+		DisplayName : fields.DisplayName,
+		Bio         : fields.Bio,
+		AvatarUrl   : fields.AvatarURL,
+	})
+	if err != nil{
+		if isUniqueViolation(err){
+			helper.RespondWithError(w,http.StatusConflict , "Handle is already taken.")
+		}else {
+			helper.RespondWithError(w,http.StatusInternalServerError , "An error occurred while processing the request.")
+		}
+		return
+	}
+	helper.RespondWithJSON(w , http.StatusOK , model.DatabaseUserToUser(user))
+}
+
+// isUniqueViolation reports whether err is Postgres rejecting a duplicate value for a UNIQUE column.
+func isUniqueViolation(err error) bool{
+	var pqErr *pq.Error
+	return errors.As(err , &pqErr) && pqErr.Code == "23505"
+}
+
+// respondWithProfile writes the public profile of a user looked up by one of the Find queries.
+func respondWithProfile(w http.ResponseWriter , user database.User , err error){
+	if err != nil{
+		if errors.Is(err , sql.ErrNoRows){
+			helper.RespondWithError(w , http.StatusNotFound , "User not found.")
+		}else {
+			helper.RespondWithError(w, http.StatusInternalServerError, "An unexpected error occurred.")
+		}
+		return
+	}
+	helper.RespondWithJSON(w , http.StatusOK , model.DatabaseUserToProfile(user))
+}
@@ -0,0 +1,150 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/Abo-Omar-74/httpServer/helper"
+	"github.com/Abo-Omar-74/httpServer/internal/auth"
+	"github.com/Abo-Omar-74/httpServer/internal/database"
+	"github.com/Abo-Omar-74/httpServer/model"
+	"github.com/google/uuid"
+)
+
+// reactionTypes are the reactions a user may leave on a post.
+var reactionTypes = map[string]bool{
+	"like"  : true,
+	"love"  : true,
+	"laugh" : true,
+	"wow"   : true,
+	"sad"   : true,
+	"angry" : true,
+}
+
+// AddReactionHandler adds the user's reaction of the given type to a post. Reacting twice with the same type is a no-op.
+func (h *Handler) AddReactionHandler(w http.ResponseWriter , r *http.Request , jwtUserID uuid.UUID){
+	if r.Method != http.MethodPut{
+		helper.RespondWithError(w,http.StatusMethodNotAllowed , "Only PUT requests are supported.")
+		return
+	}
+
+	dbPost , reactionType , ok := h.reactionTarget(w , r)
+	if !ok{
+		return
+	}
+
+	err := h.Cfg.Db.AddPostReaction(r.Context() , database.AddPostReactionParams{
+		PostID : dbPost.ID,
+		UserID : jwtUserID,
+		Type   : reactionType,
+	})
+	if err != nil{
+		helper.RespondWithError(w , http.StatusInternalServerError , "Unable to add reaction.")
+		return
+	}
+
+	h.respondWithPost(w , r , dbPost)
+}
+
+// RemoveReactionHandler takes back the user's reaction of the given type from a post.
+func (h *Handler) RemoveReactionHandler(w http.ResponseWriter , r *http.Request , jwtUserID uuid.UUID){
+	if r.Method != http.MethodDelete{
+		helper.RespondWithError(w,http.StatusMethodNotAllowed , "Only DELETE requests are supported.")
+		return
+	}
+
+	dbPost , reactionType , ok := h.reactionTarget(w , r)
+	if !ok{
+		return
+	}
+
+	removed , err := h.Cfg.Db.DeletePostReaction(r.Context() , database.DeletePostReactionParams{
+		PostID : dbPost.ID,
+		UserID : jwtUserID,
+		Type   : reactionType,
+	})
+	if err != nil{
+		helper.RespondWithError(w , http.StatusInternalServerError , "Unable to remove reaction.")
+		return
+	}
+	if removed == 0{
+		helper.RespondWithError(w , http.StatusNotFound , "Reaction not found.")
+		return
+	}
+	helper.RespondWithJSON(w , http.StatusNoContent , nil)
+}
+
+// reactionTarget reads the post and reaction type from the path. It responds with an error and returns false when either is invalid.
+func (h *Handler) reactionTarget(w http.ResponseWriter , r *http.Request) (database.Post , string , bool){
+	id , err := uuid.Parse(r.PathValue("postID"))
+	if err != nil {
+		helper.RespondWithError(w,http.StatusBadRequest , "Invalid request parameters.")
+		return database.Post{} , "" , false
+	}
+
+	reactionType := r.PathValue("type")
+	if !reactionTypes[reactionType]{
+		helper.RespondWithError(w,http.StatusBadRequest , "Unknown reaction type.")
+		return database.Post{} , "" , false
+	}
+
+	post , err := h.Cfg.Db.GetPost(r.Context() , id)
+	if err != nil{
+		if errors.Is(err , sql.ErrNoRows){
+			helper.RespondWithError(w , http.StatusNotFound , "Post not found.")
+		}else {
+			helper.RespondWithError(w, http.StatusInternalServerError, "An unexpected error occurred.")
+		}
+		return database.Post{} , "" , false
+	}
+	return post , reactionType , true
+}
+
+// withReactions fills in the reaction counts of the posts, and the viewer's own reactions
+// when the request carries an access token.
+func (h *Handler) withReactions(r *http.Request , posts ...*model.Post) error{
+	ids := make([]uuid.UUID , 0 , len(posts))
+	byID := make(map[uuid.UUID]*model.Post , len(posts))
+	for _ , post := range posts{
+		// Tombstones don't show reactions.
+		if post.Deleted{
+			continue
+		}
+		ids = append(ids , post.ID)
+		byID[post.ID] = post
+	}
+	if len(ids) == 0{
+		return nil
+	}
+
+	counts , err := h.Cfg.Db.GetPostReactionCounts(r.Context() , ids)
+	if err != nil{
+		return err
+	}
+	for _ , count := range counts{
+		byID[count.PostID].Reactions[count.Type] = count.Count
+	}
+
+	claims , ok := auth.ClaimsFromContext(r.Context())
+	if !ok{
+		return nil
+	}
+	viewerID , err := claims.UserID()
+	if err != nil{
+		return nil
+	}
+	own , err := h.Cfg.Db.GetViewerPostReactions(r.Context() , database.GetViewerPostReactionsParams{
+		UserID  : viewerID,
+		PostIds : ids,
+	})
+	if err != nil{
+		return err
+	}
+	for _ , reaction := range own{
+		post := byID[reaction.PostID]
+		post.ViewerReacted = true
+		post.ViewerReactions = append(post.ViewerReactions , reaction.Type)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/Abo-Omar-74/httpServer/helper"
+	"github.com/Abo-Omar-74/httpServer/internal/database"
+	"github.com/Abo-Omar-74/httpServer/internal/pagination"
+	"github.com/Abo-Omar-74/httpServer/model"
+	"github.com/google/uuid"
+)
+
+// GetRepliesHandler retrieves a page of direct replies to a post, oldest first.
+// Deleted replies that have replies of their own stay in the thread as tombstones.
+func (h *Handler) GetRepliesHandler(w http.ResponseWriter , r *http.Request){
+	if r.Method != http.MethodGet{
+		helper.RespondWithError(w,http.StatusMethodNotAllowed , "Only GET requests are allowed")
+		return
+	}
+
+	id , err := uuid.Parse(r.PathValue("postID"))
+	if err != nil {
+		helper.RespondWithError(w,http.StatusBadRequest , "Invalid request parameters.")
+		return 
+	}
+
+	limit , err := pagination.ParseLimit(r.URL.Query().Get("limit"))
+	if err != nil{
+		helper.RespondWithError(w , http.StatusBadRequest , "Limit must be between 1 and 100.")
+		return
+	}
+
+	cursorCreatedAt , cursorID , err := cursorParams(r)
+	if err != nil{
+		helper.RespondWithError(w , http.StatusBadRequest , "Invalid cursor.")
+		return
+	}
+
+	_ , err = h.getPostOrTombstone(r , id)
+	if err != nil{
+		if errors.Is(err , sql.ErrNoRows){
+			helper.RespondWithError(w , http.StatusNotFound , "Post not found.")
+		}else {
+			helper.RespondWithError(w, http.StatusInternalServerError, "An unexpected error occurred.")
+		}
+		return
+	}
+
+	// One extra row tells whether another page follows.
+	dbReplies , err := h.Cfg.Db.ListReplies(r.Context() , database.ListRepliesParams{
+		ParentID        : uuid.NullUUID{UUID: id , Valid: true},
+		CursorCreatedAt : cursorCreatedAt,
+		CursorID        : cursorID,
+		Limit           : int32(limit + 1),
+	})
+	if err != nil{
+		helper.RespondWithError(w , http.StatusInternalServerError , "Failed to fetch replies.")
+		return
+	}
+
+	page := model.PostsPage{}
+	if len(dbReplies) > limit{
+		dbReplies = dbReplies[:limit]
+		last := dbReplies[len(dbReplies)-1]
+		page.NextCursor = pagination.Cursor{CreatedAt: last.CreatedAt , ID: last.ID}.Encode()
+	}
+	page.Posts = model.DatabasePostsToPosts(dbReplies)
+	if err := h.withPostDetails(r , postPointers(page.Posts)...); err != nil{
+		helper.RespondWithError(w , http.StatusInternalServerError , "Failed to fetch replies.")
+		return
+	}
+	helper.RespondWithJSON(w , http.StatusOK , page)
+}
+
+// getPostOrTombstone returns a live post, or a deleted one that is kept because it has replies.
+func (h *Handler) getPostOrTombstone(r *http.Request , id uuid.UUID) (database.Post , error){
+	post , err := h.Cfg.Db.GetPost(r.Context() , id)
+	if errors.Is(err , sql.ErrNoRows){
+		return h.Cfg.Db.GetPostTombstone(r.Context() , id)
+	}
+	return post , err
+}
+
+// withReplyCounts fills in how many live replies each post has.
+func (h *Handler) withReplyCounts(r *http.Request , posts ...*model.Post) error{
+	if len(posts) == 0{
+		return nil
+	}
+	ids := make([]uuid.UUID , 0 , len(posts))
+	byID := make(map[uuid.UUID]*model.Post , len(posts))
+	for _ , post := range posts{
+		ids = append(ids , post.ID)
+		byID[post.ID] = post
+	}
+
+	counts , err := h.Cfg.Db.GetReplyCounts(r.Context() , ids)
+	if err != nil{
+		return err
+	}
+	for _ , count := range counts{
+		if post , ok := byID[count.ParentID.UUID]; ok{
+			post.ReplyCount = count.Count
+		}
+	}
+	return nil
+}
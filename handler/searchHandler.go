@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Abo-Omar-74/httpServer/helper"
+	"github.com/Abo-Omar-74/httpServer/internal/database"
+	"github.com/Abo-Omar-74/httpServer/internal/pagination"
+	"github.com/Abo-Omar-74/httpServer/model"
+	"github.com/google/uuid"
+)
+
+// SearchPostsHandler runs a full-text search over post bodies, best matches first.
+// It supports web-search syntax in q ("quoted phrases", -excluded words, or) and
+// optional author_id, since and until filters; dates may be RFC 3339 or YYYY-MM-DD.
+func (h *Handler) SearchPostsHandler(w http.ResponseWriter , r *http.Request){
+	if r.Method != http.MethodGet{
+		helper.RespondWithError(w,http.StatusMethodNotAllowed , "Only GET requests are allowed")
+		return
+	}
+
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == ""{
+		helper.RespondWithError(w , http.StatusBadRequest , "Query parameter 'q' is required.")
+		return
+	}
+
+	limit , err := pagination.ParseLimit(r.URL.Query().Get("limit"))
+	if err != nil{
+		helper.RespondWithError(w , http.StatusBadRequest , "Limit must be between 1 and 100.")
+		return
+	}
+	offset := 0
+	if offsetStr := r.URL.Query().Get("offset"); offsetStr != ""{
+		offset , err = strconv.Atoi(offsetStr)
+		if err != nil || offset < 0{
+			helper.RespondWithError(w , http.StatusBadRequest , "Offset must be a non-negative integer.")
+			return
+		}
+	}
+
+	params := database.SearchPostsParams{
+		Query  : query,
+		Limit  : int32(limit + 1),
+		Offset : int32(offset),
+	}
+	if authorIdStr := r.URL.Query().Get("author_id"); authorIdStr != ""{
+		authorID , err := uuid.Parse(authorIdStr)
+		if err != nil{
+			helper.RespondWithError(w , http.StatusBadRequest , "Invalid request parameters.")
+			return
+		}
+		params.AuthorID = uuid.NullUUID{UUID: authorID , Valid: true}
+	}
+	params.Since , err = parseDateParam(r.URL.Query().Get("since"))
+	if err != nil{
+		helper.RespondWithError(w , http.StatusBadRequest , "Invalid 'since' date.")
+		return
+	}
+	params.Until , err = parseDateParam(r.URL.Query().Get("until"))
+	if err == nil && params.Until.Valid && isDateOnly(r.URL.Query().Get("until")){
+		// until is exclusive, so a bare date has to end after that whole day.
+		params.Until.Time = params.Until.Time.Add(24 * time.Hour)
+	}
+	if err != nil{
+		helper.RespondWithError(w , http.StatusBadRequest , "Invalid 'until' date.")
+		return
+	}
+
+	rows , err := h.Cfg.Db.SearchPosts(r.Context() , params)
+	if err != nil{
+		helper.RespondWithError(w , http.StatusInternalServerError , "Failed to search posts.")
+		return
+	}
+
+	page := model.PostSearchPage{Results: []model.PostSearchResult{}}
+	if len(rows) > limit{
+		rows = rows[:limit]
+		nextOffset := offset + limit
+		page.NextOffset = &nextOffset
+	}
+	for _ , row := range rows{
+		page.Results = append(page.Results , model.DatabaseSearchRowToResult(row))
+	}
+	posts := make([]*model.Post , len(page.Results))
+	for i := range page.Results{
+		posts[i] = &page.Results[i].Post
+	}
+	if err := h.withPostDetails(r , posts...); err != nil{
+		helper.RespondWithError(w , http.StatusInternalServerError , "Failed to fetch reactions.")
+		return
+	}
+	helper.RespondWithJSON(w , http.StatusOK , page)
+}
+
+// isDateOnly reports whether a date parameter was given as YYYY-MM-DD rather than a full timestamp.
+func isDateOnly(value string) bool{
+	_ , err := time.Parse(time.DateOnly , value)
+	return err == nil
+}
+
+// parseDateParam reads an optional RFC 3339 timestamp or YYYY-MM-DD date, in UTC.
+func parseDateParam(value string) (sql.NullTime , error){
+	if value == ""{
+		return sql.NullTime{} , nil
+	}
+	t , err := time.Parse(time.RFC3339 , value)
+	if err != nil{
+		t , err = time.Parse(time.DateOnly , value)
+		if err != nil{
+			return sql.NullTime{} , err
+		}
+	}
+	return sql.NullTime{Time: t.UTC() , Valid: true} , nil
+}
@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/Abo-Omar-74/httpServer/helper"
+	"github.com/Abo-Omar-74/httpServer/model"
+	"github.com/google/uuid"
+)
+
+// GetSessionsHandler lists the active sessions of the authenticated user.
+func (h *Handler) GetSessionsHandler(w http.ResponseWriter , r *http.Request , jwtUserID uuid.UUID){
+	if r.Method != http.MethodGet{
+		helper.RespondWithError(w,http.StatusMethodNotAllowed , "Only GET requests are allowed")
+		return
+	}
+
+	dbSessions , err := h.Cfg.Db.GetActiveSessionsByUserID(r.Context() , jwtUserID)
+	if err != nil{
+		helper.RespondWithError(w , http.StatusInternalServerError , "Failed to fetch sessions.")
+		return
+	}
+
+	sessions := []model.Session{}
+	for _ , session := range dbSessions{
+		sessions = append(sessions , model.DatabaseSessionToSession(session))
+	}
+	helper.RespondWithJSON(w , http.StatusOK , sessions)
+}
+
+// DeleteSessionHandler revokes one session of the authenticated user and all of its refresh tokens.
+func (h *Handler) DeleteSessionHandler(w http.ResponseWriter , r *http.Request , jwtUserID uuid.UUID){
+	if r.Method != http.MethodDelete{
+		helper.RespondWithError(w,http.StatusMethodNotAllowed , "Only DELETE requests are supported.")
+		return
+	}
+
+	idString := r.PathValue("sessionID")
+	if idString == ""{
+		helper.RespondWithError(w,http.StatusBadRequest , "Session ID is required.")
+		return
+	}
+	id , err := uuid.Parse(idString)
+	if err != nil{
+		helper.RespondWithError(w,http.StatusBadRequest , "Invalid request parameters.")
+		return
+	}
+
+	session , err := h.Cfg.Db.GetSession(r.Context() , id)
+	if err != nil{
+		if errors.Is(err , sql.ErrNoRows){
+			helper.RespondWithError(w , http.StatusNotFound , "Session not found.")
+		}else {
+			helper.RespondWithError(w, http.StatusInternalServerError, "An unexpected error occurred.")
+		}
+		return
+	}
+	// Other users' sessions are reported as missing so their IDs can't be probed.
+	if session.UserID != jwtUserID{
+		helper.RespondWithError(w , http.StatusNotFound , "Session not found.")
+		return
+	}
+
+	err = h.revokeSession(r.Context() , session.ID)
+	if err != nil{
+		helper.RespondWithError(w , http.StatusInternalServerError , "Unable to revoke session.")
+		return
+	}
+	helper.RespondWithJSON(w , http.StatusNoContent , nil)
+}
+
+// DeleteAllSessionsHandler logs the authenticated user out everywhere by revoking every session and refresh token.
+func (h *Handler) DeleteAllSessionsHandler(w http.ResponseWriter , r *http.Request , jwtUserID uuid.UUID){
+	if r.Method != http.MethodDelete{
+		helper.RespondWithError(w,http.StatusMethodNotAllowed , "Only DELETE requests are supported.")
+		return
+	}
+
+	err := h.revokeAllSessions(r.Context() , jwtUserID)
+	if err != nil{
+		helper.RespondWithError(w , http.StatusInternalServerError , "Unable to revoke sessions.")
+		return
+	}
+	helper.RespondWithJSON(w , http.StatusNoContent , nil)
+}
+
+// revokeSession marks a session as revoked together with every refresh token in its family.
+func (h *Handler) revokeSession(ctx context.Context , sessionID uuid.UUID) error{
+	err := h.Cfg.Db.RevokeSession(ctx , sessionID)
+	if err != nil{
+		return err
+	}
+	return h.Cfg.Db.RevokeRefreshTokenFamily(ctx , sessionID)
+}
+
+// revokeAllSessions revokes every session and refresh token that belongs to the user.
+func (h *Handler) revokeAllSessions(ctx context.Context , userID uuid.UUID) error{
+	err := h.Cfg.Db.RevokeSessionsByUserID(ctx , userID)
+	if err != nil{
+		return err
+	}
+	return h.Cfg.Db.RevokeRefreshTokensByUserID(ctx , userID)
+}
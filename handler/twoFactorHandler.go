@@ -0,0 +1,355 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/Abo-Omar-74/httpServer/helper"
+	"github.com/Abo-Omar-74/httpServer/internal/auth"
+	"github.com/Abo-Omar-74/httpServer/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	recoveryCodeCount = 10
+	// maxChallengeAttempts caps guesses against one challenge token; the user has to log in again after that.
+	maxChallengeAttempts = 5
+)
+
+type TwoFactorChallengeResponse struct{
+	TwoFactorRequired bool `json:"two_factor_required"`
+	ChallengeToken string `json:"challenge_token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type TwoFactorEnrollResponse struct{
+	Secret string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+type RecoveryCodesResponse struct{
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type secondFactorParameters struct{
+	Code string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+// TwoFactorLoginHandler exchanges a challenge token from LoginHandler and a TOTP or recovery code for real tokens.
+func (h *Handler) TwoFactorLoginHandler(w http.ResponseWriter , r *http.Request){
+	if r.Method != http.MethodPost{
+		helper.RespondWithError(w,http.StatusMethodNotAllowed , "Only POST requests are supported.")
+		return
+	}
+
+	type parameters struct{
+		ChallengeToken string `json:"challenge_token"`
+		secondFactorParameters
+	}
+	var params parameters
+
+	body , err := io.ReadAll(r.Body)
+	if err != nil{
+		helper.RespondWithError(w,http.StatusBadRequest , "Invalid request body.")
+		return
+	}
+	err = json.Unmarshal(body , &params)
+	if err != nil || params.ChallengeToken == ""{
+		helper.RespondWithError(w,http.StatusBadRequest , "Invalid JSON format.")
+		return
+	}
+
+	challengeHash := auth.HashToken(params.ChallengeToken)
+	challenge , err := h.Cfg.Db.AttemptTwoFactorChallenge(r.Context() , challengeHash)
+	if err != nil{
+		if errors.Is(err , sql.ErrNoRows){
+			helper.RespondWithError(w , http.StatusUnauthorized , "Challenge is invalid or has expired.")
+		}else {
+			helper.RespondWithError(w , http.StatusInternalServerError , "An error occurred while processing your request.")
+		}
+		return
+	}
+
+	user , err := h.Cfg.Db.FindUserByID(r.Context() , challenge.UserID)
+	if err != nil{
+		helper.RespondWithError(w , http.StatusUnauthorized , "Challenge is invalid or has expired.")
+		return
+	}
+	// New challenges are cheap to get with a known password, so the login lockout
+	// has to cover the second factor as well.
+	if !h.checkLoginLockout(w , r , user.Email){
+		return
+	}
+
+	if challenge.Attempts > maxChallengeAttempts{
+		h.Cfg.Db.UseTwoFactorChallenge(r.Context() , challengeHash)
+		helper.RespondWithError(w , http.StatusUnauthorized , "Too many attempts, please log in again.")
+		return
+	}
+
+	credential , err := h.Cfg.Db.GetTotpCredential(r.Context() , challenge.UserID)
+	if err != nil || !credential.ConfirmedAt.Valid{
+		helper.RespondWithError(w , http.StatusUnauthorized , "Challenge is invalid or has expired.")
+		return
+	}
+
+	ok , err := h.verifySecondFactor(r.Context() , credential , params.secondFactorParameters)
+	if err != nil{
+		helper.RespondWithError(w , http.StatusInternalServerError , "An error occurred while processing your request.")
+		return
+	}
+	if !ok{
+		h.recordLoginFailure(r , user.Email)
+		helper.RespondWithError(w , http.StatusUnauthorized , "Invalid authentication code.")
+		return
+	}
+
+	used , err := h.Cfg.Db.UseTwoFactorChallenge(r.Context() , challengeHash)
+	if err != nil{
+		helper.RespondWithError(w , http.StatusInternalServerError , "An error occurred while processing your request.")
+		return
+	}
+	if used == 0{
+		helper.RespondWithError(w , http.StatusUnauthorized , "Challenge is invalid or has expired.")
+		return
+	}
+	h.respondWithLogin(w , r , user)
+}
+
+// EnrollTwoFactorHandler creates a new TOTP secret for the authenticated user.
+// It only takes effect once ConfirmTwoFactorHandler receives a valid code for it.
+func (h *Handler) EnrollTwoFactorHandler(w http.ResponseWriter , r *http.Request , jwtUserID uuid.UUID){
+	if r.Method != http.MethodPost{
+		helper.RespondWithError(w,http.StatusMethodNotAllowed , "Only POST requests are supported.")
+		return
+	}
+
+	user , err := h.Cfg.Db.FindUserByID(r.Context() , jwtUserID)
+	if err != nil{
+		helper.RespondWithError(w,http.StatusUnauthorized ,  "Unauthorized: Invalid credentials.")
+		return
+	}
+
+	credential , err := h.Cfg.Db.GetTotpCredential(r.Context() , jwtUserID)
+	if err == nil && credential.ConfirmedAt.Valid{
+		helper.RespondWithError(w , http.StatusConflict , "Two-factor authentication is already enabled.")
+		return
+	}else if err != nil && !errors.Is(err , sql.ErrNoRows){
+		helper.RespondWithError(w , http.StatusInternalServerError , "An error occurred while processing your request.")
+		return
+	}
+
+	secret , err := auth.GenerateTOTPSecret()
+	if err != nil{
+		helper.RespondWithError(w , http.StatusInternalServerError , "An error occurred while processing your request.")
+		return
+	}
+	_ , err = h.Cfg.Db.UpsertTotpCredential(r.Context() , database.UpsertTotpCredentialParams{UserID: jwtUserID , Secret: secret})
+	if err != nil{
+		helper.RespondWithError(w , http.StatusInternalServerError , "An error occurred while processing your request.")
+		return
+	}
+
+	helper.RespondWithJSON(w , http.StatusOK , TwoFactorEnrollResponse{
+		Secret          : secret,
+		ProvisioningURI : auth.TOTPProvisioningURI(h.Cfg.TOTPIssuer , user.Email , secret),
+	})
+}
+
+// ConfirmTwoFactorHandler enables TOTP after the first valid code and returns fresh recovery codes.
+func (h *Handler) ConfirmTwoFactorHandler(w http.ResponseWriter , r *http.Request , jwtUserID uuid.UUID){
+	if r.Method != http.MethodPost{
+		helper.RespondWithError(w,http.StatusMethodNotAllowed , "Only POST requests are supported.")
+		return
+	}
+
+	var params secondFactorParameters
+	body , err := io.ReadAll(r.Body)
+	if err != nil{
+		helper.RespondWithError(w,http.StatusBadRequest , "Failed to read request body.")
+		return
+	}
+	err = json.Unmarshal(body , &params)
+	if err != nil{
+		helper.RespondWithError(w,http.StatusBadRequest , "Invalid request format.")
+		return
+	}
+
+	credential , err := h.Cfg.Db.GetTotpCredential(r.Context() , jwtUserID)
+	if err != nil{
+		if errors.Is(err , sql.ErrNoRows){
+			helper.RespondWithError(w , http.StatusNotFound , "Two-factor enrollment not started.")
+		}else {
+			helper.RespondWithError(w , http.StatusInternalServerError , "An error occurred while processing your request.")
+		}
+		return
+	}
+	if credential.ConfirmedAt.Valid{
+		helper.RespondWithError(w , http.StatusConflict , "Two-factor authentication is already enabled.")
+		return
+	}
+
+	step , ok := auth.ValidateTOTP(credential.Secret , params.Code , time.Now())
+	if !ok{
+		helper.RespondWithError(w , http.StatusBadRequest , "Invalid authentication code.")
+		return
+	}
+	err = h.Cfg.Db.ConfirmTotpCredential(r.Context() , database.ConfirmTotpCredentialParams{UserID: jwtUserID , LastUsedStep: step})
+	if err != nil{
+		helper.RespondWithError(w , http.StatusInternalServerError , "An error occurred while processing your request.")
+		return
+	}
+
+	codes , err := h.createRecoveryCodes(r.Context() , jwtUserID)
+	if err != nil{
+		helper.RespondWithError(w , http.StatusInternalServerError , "An error occurred while processing your request.")
+		return
+	}
+	helper.RespondWithJSON(w , http.StatusOK , RecoveryCodesResponse{codes})
+}
+
+// RegenerateRecoveryCodesHandler replaces all recovery codes after checking a current TOTP code.
+func (h *Handler) RegenerateRecoveryCodesHandler(w http.ResponseWriter , r *http.Request , jwtUserID uuid.UUID){
+	if r.Method != http.MethodPost{
+		helper.RespondWithError(w,http.StatusMethodNotAllowed , "Only POST requests are supported.")
+		return
+	}
+
+	credential , ok := h.requireSecondFactor(w , r , jwtUserID , false)
+	if !ok{
+		return
+	}
+
+	codes , err := h.createRecoveryCodes(r.Context() , credential.UserID)
+	if err != nil{
+		helper.RespondWithError(w , http.StatusInternalServerError , "An error occurred while processing your request.")
+		return
+	}
+	helper.RespondWithJSON(w , http.StatusOK , RecoveryCodesResponse{codes})
+}
+
+// DisableTwoFactorHandler turns TOTP off after checking a TOTP or recovery code.
+func (h *Handler) DisableTwoFactorHandler(w http.ResponseWriter , r *http.Request , jwtUserID uuid.UUID){
+	if r.Method != http.MethodPost{
+		helper.RespondWithError(w,http.StatusMethodNotAllowed , "Only POST requests are supported.")
+		return
+	}
+
+	_ , ok := h.requireSecondFactor(w , r , jwtUserID , true)
+	if !ok{
+		return
+	}
+
+	err := h.Cfg.Db.DeleteTotpCredential(r.Context() , jwtUserID)
+	if err != nil{
+		helper.RespondWithError(w , http.StatusInternalServerError , "An error occurred while processing your request.")
+		return
+	}
+	err = h.Cfg.Db.DeleteRecoveryCodesByUserID(r.Context() , jwtUserID)
+	if err != nil{
+		helper.RespondWithError(w , http.StatusInternalServerError , "An error occurred while processing your request.")
+		return
+	}
+	helper.RespondWithJSON(w , http.StatusNoContent , nil)
+}
+
+// requireSecondFactor reads a code from the request body and checks it against the user's enabled TOTP credential.
+// It writes the error response itself and returns false when the request must stop.
+func (h *Handler) requireSecondFactor(w http.ResponseWriter , r *http.Request , userID uuid.UUID , allowRecoveryCode bool) (database.TotpCredential , bool){
+	var params secondFactorParameters
+	body , err := io.ReadAll(r.Body)
+	if err != nil{
+		helper.RespondWithError(w,http.StatusBadRequest , "Failed to read request body.")
+		return database.TotpCredential{} , false
+	}
+	err = json.Unmarshal(body , &params)
+	if err != nil{
+		helper.RespondWithError(w,http.StatusBadRequest , "Invalid request format.")
+		return database.TotpCredential{} , false
+	}
+	if !allowRecoveryCode{
+		params.RecoveryCode = ""
+	}
+
+	credential , err := h.Cfg.Db.GetTotpCredential(r.Context() , userID)
+	if err != nil || !credential.ConfirmedAt.Valid{
+		helper.RespondWithError(w , http.StatusNotFound , "Two-factor authentication is not enabled.")
+		return database.TotpCredential{} , false
+	}
+
+	ok , err := h.verifySecondFactor(r.Context() , credential , params)
+	if err != nil{
+		helper.RespondWithError(w , http.StatusInternalServerError , "An error occurred while processing your request.")
+		return database.TotpCredential{} , false
+	}
+	if !ok{
+		helper.RespondWithError(w , http.StatusUnauthorized , "Invalid authentication code.")
+		return database.TotpCredential{} , false
+	}
+	return credential , true
+}
+
+// verifySecondFactor accepts either a TOTP code that hasn't been used before or an unused recovery code.
+func (h *Handler) verifySecondFactor(ctx context.Context , credential database.TotpCredential , params secondFactorParameters) (bool , error){
+	if params.RecoveryCode != ""{
+		used , err := h.Cfg.Db.UseRecoveryCode(ctx , database.UseRecoveryCodeParams{
+			UserID   : credential.UserID,
+			CodeHash : auth.HashToken(auth.NormalizeRecoveryCode(params.RecoveryCode)),
+		})
+		return used == 1 , err
+	}
+
+	step , ok := auth.ValidateTOTP(credential.Secret , params.Code , time.Now())
+	if !ok{
+		return false , nil
+	}
+	// Recording the step atomically means a code can't be replayed, even by a concurrent request.
+	used , err := h.Cfg.Db.UseTotpStep(ctx , database.UseTotpStepParams{UserID: credential.UserID , LastUsedStep: step})
+	return used == 1 , err
+}
+
+// createRecoveryCodes replaces the user's recovery codes and returns the new ones in plain text.
+func (h *Handler) createRecoveryCodes(ctx context.Context , userID uuid.UUID) ([]string , error){
+	codes , err := auth.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil{
+		return nil , err
+	}
+	err = h.Cfg.Db.DeleteRecoveryCodesByUserID(ctx , userID)
+	if err != nil{
+		return nil , err
+	}
+	for _ , code := range codes{
+		err = h.Cfg.Db.CreateRecoveryCode(ctx , database.CreateRecoveryCodeParams{CodeHash: auth.HashToken(code) , UserID: userID})
+		if err != nil{
+			return nil , err
+		}
+	}
+	return codes , nil
+}
+
+// respondWithTwoFactorChallenge answers a correct password with a short-lived challenge token instead of real tokens.
+func (h *Handler) respondWithTwoFactorChallenge(w http.ResponseWriter , r *http.Request , user database.User){
+	token , err := auth.MakeRefreshToken()
+	if err != nil{
+		helper.RespondWithError(w , http.StatusInternalServerError , "An error occurred while processing your request.")
+		return
+	}
+	// The database sets expires_at from its own clock, which is the one AttemptTwoFactorChallenge checks.
+	_ , err = h.Cfg.Db.CreateTwoFactorChallenge(r.Context() , database.CreateTwoFactorChallengeParams{
+		TokenHash  : auth.HashToken(token),
+		UserID     : user.ID,
+		TtlSeconds : h.Cfg.TwoFactorChallengeTTL.Seconds(),
+	})
+	if err != nil{
+		helper.RespondWithError(w , http.StatusInternalServerError , "An error occurred while processing your request.")
+		return
+	}
+	expiresAt := time.Now().Add(h.Cfg.TwoFactorChallengeTTL)
+	helper.RespondWithJSON(w , http.StatusOK , TwoFactorChallengeResponse{true , token , expiresAt})
+}
@@ -0,0 +1,272 @@
+package handler
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/Abo-Omar-74/httpServer/helper"
+	"github.com/Abo-Omar-74/httpServer/internal/database"
+	"github.com/Abo-Omar-74/httpServer/internal/media"
+	"github.com/Abo-Omar-74/httpServer/model"
+	"github.com/google/uuid"
+)
+
+// multipartOverhead is the room left for multipart headers and boundaries around the file itself.
+const multipartOverhead = 64 * 1024
+
+// UploadAvatarHandler replaces the user's avatar with the image in the "file" field of a multipart form.
+func (h *Handler) UploadAvatarHandler(w http.ResponseWriter , r *http.Request , jwtUserID uuid.UUID){
+	if r.Method != http.MethodPut{
+		helper.RespondWithError(w,http.StatusMethodNotAllowed , "Only PUT requests are supported.")
+		return
+	}
+
+	img , ok := readImageUpload(w , r , h.Cfg.MaxAvatarBytes)
+	if !ok{
+		return
+	}
+
+	user , err := h.Cfg.Db.FindUserByID(r.Context() , jwtUserID)
+	if err != nil{
+		helper.RespondWithError(w,http.StatusUnauthorized , "Unauthorized")
+		return
+	}
+
+	key := "avatars/" + uuid.NewString() + img.Ext
+	err = h.Cfg.Storage.Put(r.Context() , key , bytes.NewReader(img.Data))
+	if err != nil{
+		helper.RespondWithError(w , http.StatusInternalServerError , "Unable to store the file.")
+		return
+	}
+
+	updated , err := h.Cfg.Db.SetUserAvatar(r.Context() , database.SetUserAvatarParams{
+		ID        : user.ID,
+		AvatarUrl : h.Cfg.Storage.URL(key),
+		AvatarKey : key,
+	})
+	if err != nil{
+		h.deleteStoredFile(r , key)
+		helper.RespondWithError(w , http.StatusInternalServerError , "An error occurred while processing the request.")
+		return
+	}
+
+	// Only the file we stored for this user is deleted. avatar_url can be set to any
+	// URL, including someone else's upload, so a key is never derived from it.
+	if user.AvatarKey != ""{
+		h.deleteStoredFile(r , user.AvatarKey)
+	}
+	helper.RespondWithJSON(w , http.StatusOK , model.DatabaseUserToUser(updated))
+}
+
+// UploadAttachmentHandler adds the image in the "file" field of a multipart form to a post. Only the author may do so.
+func (h *Handler) UploadAttachmentHandler(w http.ResponseWriter , r *http.Request , jwtUserID uuid.UUID){
+	if r.Method != http.MethodPost{
+		helper.RespondWithError(w,http.StatusMethodNotAllowed , "Only POST requests are supported.")
+		return
+	}
+
+	id , err := uuid.Parse(r.PathValue("postID"))
+	if err != nil {
+		helper.RespondWithError(w,http.StatusBadRequest , "Invalid request parameters.")
+		return 
+	}
+	post , err := h.Cfg.Db.GetPost(r.Context() , id)
+	if err != nil{
+		helper.RespondWithError(w,http.StatusNotFound , "Post not found.")
+		return
+	}
+	if jwtUserID != post.UserID{
+		helper.RespondWithError(w,http.StatusForbidden ,"You are not allowed to change this post.")
+		return
+	}
+
+	img , ok := readImageUpload(w , r , h.Cfg.MaxAttachmentBytes)
+	if !ok{
+		return
+	}
+
+	// The post row stays locked until commit, so concurrent uploads can't both pass the limit.
+	tx , err := h.Cfg.SqlDB.BeginTx(r.Context() , nil)
+	if err != nil{
+		helper.RespondWithError(w , http.StatusInternalServerError , "An unexpected error occurred.")
+		return
+	}
+	defer tx.Rollback()
+	qtx := h.Cfg.Db.WithTx(tx)
+
+	_ , err = qtx.LockPostForAttachments(r.Context() , post.ID)
+	if err != nil{
+		if errors.Is(err , sql.ErrNoRows){
+			helper.RespondWithError(w,http.StatusNotFound , "Post not found.")
+		}else {
+			helper.RespondWithError(w , http.StatusInternalServerError , "An unexpected error occurred.")
+		}
+		return
+	}
+	count , err := qtx.CountPostAttachments(r.Context() , post.ID)
+	if err != nil{
+		helper.RespondWithError(w , http.StatusInternalServerError , "An unexpected error occurred.")
+		return
+	}
+	if count >= int64(h.Cfg.MaxAttachmentsPerPost){
+		helper.RespondWithError(w , http.StatusConflict , "This post already has the maximum number of attachments.")
+		return
+	}
+
+	key := "attachments/" + uuid.NewString() + img.Ext
+	err = h.Cfg.Storage.Put(r.Context() , key , bytes.NewReader(img.Data))
+	if err != nil{
+		helper.RespondWithError(w , http.StatusInternalServerError , "Unable to store the file.")
+		return
+	}
+
+	attachment , err := qtx.CreatePostAttachment(r.Context() , database.CreatePostAttachmentParams{
+		PostID      : post.ID,
+		UserID      : jwtUserID,
+		StorageKey  : key,
+		ContentType : img.ContentType,
+		SizeBytes   : int64(len(img.Data)),
+	})
+	if err == nil{
+		err = tx.Commit()
+	}
+	if err != nil{
+		h.deleteStoredFile(r , key)
+		helper.RespondWithError(w , http.StatusInternalServerError , "Unable to save the attachment.")
+		return
+	}
+	helper.RespondWithJSON(w , http.StatusCreated , model.DatabaseAttachmentToAttachment(attachment , h.Cfg.Storage.URL(key)))
+}
+
+// DeleteAttachmentHandler removes an attachment from a post. Only the author may do so.
+func (h *Handler) DeleteAttachmentHandler(w http.ResponseWriter , r *http.Request , jwtUserID uuid.UUID){
+	if r.Method != http.MethodDelete{
+		helper.RespondWithError(w,http.StatusMethodNotAllowed , "Only DELETE requests are supported.")
+		return
+	}
+
+	postID , err := uuid.Parse(r.PathValue("postID"))
+	if err != nil {
+		helper.RespondWithError(w,http.StatusBadRequest , "Invalid request parameters.")
+		return 
+	}
+	attachmentID , err := uuid.Parse(r.PathValue("attachmentID"))
+	if err != nil {
+		helper.RespondWithError(w,http.StatusBadRequest , "Invalid request parameters.")
+		return 
+	}
+	post , err := h.Cfg.Db.GetPost(r.Context() , postID)
+	if err != nil{
+		helper.RespondWithError(w,http.StatusNotFound , "Post not found.")
+		return
+	}
+	if jwtUserID != post.UserID{
+		helper.RespondWithError(w,http.StatusForbidden ,"You are not allowed to change this post.")
+		return
+	}
+
+	attachment , err := h.Cfg.Db.DeletePostAttachment(r.Context() , database.DeletePostAttachmentParams{ID: attachmentID , PostID: post.ID})
+	if err != nil{
+		if errors.Is(err , sql.ErrNoRows){
+			helper.RespondWithError(w , http.StatusNotFound , "Attachment not found.")
+		}else {
+			helper.RespondWithError(w , http.StatusInternalServerError , "Unable to delete the attachment.")
+		}
+		return
+	}
+	h.deleteStoredFile(r , attachment.StorageKey)
+	helper.RespondWithJSON(w , http.StatusNoContent , nil)
+}
+
+// readImageUpload reads the "file" field of a multipart form, at most maxBytes long, and checks and cleans it
+// with the media package. It responds with an error and returns false when the upload is unusable.
+func readImageUpload(w http.ResponseWriter , r *http.Request , maxBytes int64) (*media.Image , bool){
+	r.Body = http.MaxBytesReader(w , r.Body , maxBytes + multipartOverhead)
+	reader , err := r.MultipartReader()
+	if err != nil{
+		helper.RespondWithError(w , http.StatusBadRequest , "Expected a multipart/form-data request.")
+		return nil , false
+	}
+
+	for {
+		part , err := reader.NextPart()
+		if err == io.EOF{
+			helper.RespondWithError(w , http.StatusBadRequest , "The 'file' field is missing.")
+			return nil , false
+		}
+		if err != nil{
+			respondWithUploadReadError(w , err)
+			return nil , false
+		}
+		if part.FormName() != "file"{
+			continue
+		}
+
+		data , err := io.ReadAll(io.LimitReader(part , maxBytes + 1))
+		if err != nil{
+			respondWithUploadReadError(w , err)
+			return nil , false
+		}
+		if int64(len(data)) > maxBytes{
+			helper.RespondWithError(w , http.StatusRequestEntityTooLarge , "The file is too large.")
+			return nil , false
+		}
+
+		img , err := media.Process(data)
+		if errors.Is(err , media.ErrUnsupportedType){
+			helper.RespondWithError(w , http.StatusUnsupportedMediaType , "Only JPEG, PNG, GIF and WebP images are allowed.")
+			return nil , false
+		}
+		if err != nil{
+			helper.RespondWithError(w , http.StatusBadRequest , "The image is invalid.")
+			return nil , false
+		}
+		return img , true
+	}
+}
+
+func respondWithUploadReadError(w http.ResponseWriter , err error){
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err , &maxBytesErr){
+		helper.RespondWithError(w , http.StatusRequestEntityTooLarge , "The file is too large.")
+		return
+	}
+	helper.RespondWithError(w , http.StatusBadRequest , "Failed to read the upload.")
+}
+
+// withAttachments fills in the attachments of the posts. Tombstones keep none.
+func (h *Handler) withAttachments(r *http.Request , posts ...*model.Post) error{
+	ids := make([]uuid.UUID , 0 , len(posts))
+	byID := make(map[uuid.UUID]*model.Post , len(posts))
+	for _ , post := range posts{
+		if post.Deleted{
+			continue
+		}
+		ids = append(ids , post.ID)
+		byID[post.ID] = post
+	}
+	if len(ids) == 0{
+		return nil
+	}
+
+	attachments , err := h.Cfg.Db.GetPostAttachments(r.Context() , ids)
+	if err != nil{
+		return err
+	}
+	for _ , attachment := range attachments{
+		post := byID[attachment.PostID]
+		post.Attachments = append(post.Attachments , model.DatabaseAttachmentToAttachment(attachment , h.Cfg.Storage.URL(attachment.StorageKey)))
+	}
+	return nil
+}
+
+// deleteStoredFile removes a file that is no longer referenced. A failure only leaves an orphaned file, so it is logged.
+func (h *Handler) deleteStoredFile(r *http.Request , key string){
+	if err := h.Cfg.Storage.Delete(r.Context() , key); err != nil{
+		log.Printf("Failed to delete stored file %s: %v", key , err)
+	}
+}
@@ -4,17 +4,19 @@ import (
 	"database/sql"
 	"encoding/json"
 	"io"
+	"log"
 	"net/http"
+	"net/mail"
 	"time"
 
 	"github.com/Abo-Omar-74/httpServer/helper"
-	"github.com/Abo-Omar-74/httpServer/internal/auth"
 	"github.com/Abo-Omar-74/httpServer/internal/database"
 	"github.com/Abo-Omar-74/httpServer/model"
 	"github.com/google/uuid"
 )
 
 // CreateUserHandler handles the creation of a new user in the system.
+// New users start unverified and are sent an email verification token.
 func (h *Handler)CreateUserHandler(w http.ResponseWriter , r *http.Request){
 	if r.Method != http.MethodPost{
 		helper.RespondWithError(w,http.StatusMethodNotAllowed , "Only POST requests are supported.")
@@ -45,7 +47,13 @@ func (h *Handler)CreateUserHandler(w http.ResponseWriter , r *http.Request){
 		return
 	}
 
-	Hash , err := auth.HashPassword(params.Password)
+	address , err := mail.ParseAddress(params.Email)
+	if err != nil || address.Address != params.Email{
+		helper.RespondWithError(w , http.StatusBadRequest ,"Invalid email address.")
+		return
+	}
+
+	Hash , err := h.Cfg.PasswordHasher.Hash(params.Password)
 
 	if err != nil{
 		helper.RespondWithError(w , http.StatusInternalServerError ,"An error occurred while processing your request.")
@@ -67,6 +75,12 @@ func (h *Handler)CreateUserHandler(w http.ResponseWriter , r *http.Request){
 		helper.RespondWithError(w,http.StatusInternalServerError , "An error occurred while processing your request.")
 		return
 	}
+
+	// The account exists either way; the user can ask for another email if this one fails.
+	err = h.sendVerificationEmail(r.Context() , dbUser)
+	if err != nil{
+		log.Printf("Failed to send verification email to user %s: %v", dbUser.ID , err)
+	}
 	helper.RespondWithJSON(w,http.StatusCreated , model.DatabaseUserToUser(dbUser))
 }
 
@@ -106,7 +120,7 @@ func  (h *Handler) EditUserHandler(w http.ResponseWriter , r *http.Request , jwt
 		return
 	}
 	
-	HashedPassword , err := auth.HashPassword(params.Password)
+	HashedPassword , err := h.Cfg.PasswordHasher.Hash(params.Password)
 	if err != nil {
 		helper.RespondWithError(w,http.StatusInternalServerError , "An error occurred while processing the request.")
 		return
@@ -128,8 +142,8 @@ func  (h *Handler) EditUserHandler(w http.ResponseWriter , r *http.Request , jwt
 }
 
 
-// DeleteAllUsers handles the deletion of all users, restricted to the "dev" platform.
-func (h *Handler) DeleteAllUsers(w http.ResponseWriter , r *http.Request){
+// DeleteAllUsers handles the deletion of all users, restricted to admins on the "dev" platform.
+func (h *Handler) DeleteAllUsers(w http.ResponseWriter , r *http.Request , jwtUserID uuid.UUID){
 	if r.Method != http.MethodPost{
 		helper.RespondWithError(w,http.StatusMethodNotAllowed , "Only POST requests are supported")
 		return
@@ -139,10 +153,21 @@ func (h *Handler) DeleteAllUsers(w http.ResponseWriter , r *http.Request){
 		return
 	}
 	
-	err := h.Cfg.Db.DeleteAllUsers(r.Context())
+	// Avatars and attachments go with their users, so their files are removed too.
+	keys , err := h.Cfg.Db.ListStoredFileKeys(r.Context())
 	if err != nil{
 		helper.RespondWithError(w,http.StatusInternalServerError , "Unable to process your request")
 		return
 	}
+	err = h.Cfg.Db.DeleteAllUsers(r.Context())
+	if err != nil{
+		helper.RespondWithError(w,http.StatusInternalServerError , "Unable to process your request")
+		return
+	}
+	for _ , key := range keys{
+		if err := h.Cfg.Storage.Delete(r.Context() , key); err != nil{
+			log.Printf("Failed to delete stored file %s: %v\n" , key , err)
+		}
+	}
 	helper.RespondWithJSON(w,http.StatusOK,"All Users have been deleted successfully")
 }
\ No newline at end of file
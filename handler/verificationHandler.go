@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/Abo-Omar-74/httpServer/helper"
+	"github.com/Abo-Omar-74/httpServer/internal/auth"
+	"github.com/Abo-Omar-74/httpServer/internal/database"
+	"github.com/Abo-Omar-74/httpServer/internal/mailer"
+	"github.com/Abo-Omar-74/httpServer/model"
+	"github.com/google/uuid"
+)
+
+// VerifyEmailHandler confirms a user's email address with a token sent by email.
+// Access tokens issued before verification keep saying the email is unverified until they are refreshed.
+func (h *Handler) VerifyEmailHandler(w http.ResponseWriter , r *http.Request){
+	if r.Method != http.MethodPost{
+		helper.RespondWithError(w,http.StatusMethodNotAllowed , "Only POST requests are supported.")
+		return
+	}
+
+	type parameters struct{
+		Token string `json:"token"`
+	}
+	var params parameters
+
+	body , err := io.ReadAll(r.Body)
+	if err != nil{
+		helper.RespondWithError(w,http.StatusBadRequest , "Failed to read request body.")
+		return
+	}
+	err = json.Unmarshal(body , &params)
+	if err != nil || params.Token == ""{
+		helper.RespondWithError(w,http.StatusBadRequest , "Invalid request format.")
+		return
+	}
+
+	token , err := h.Cfg.Db.UseEmailVerificationToken(r.Context() , auth.HashToken(params.Token))
+	if err != nil{
+		if errors.Is(err , sql.ErrNoRows){
+			helper.RespondWithError(w , http.StatusBadRequest , "Verification token is invalid or has expired.")
+		}else {
+			helper.RespondWithError(w , http.StatusInternalServerError , "An error occurred while processing your request.")
+		}
+		return
+	}
+
+	// The email must still be the one the token was sent to.
+	user , err := h.Cfg.Db.MarkUserEmailVerified(r.Context() , database.MarkUserEmailVerifiedParams{
+		ID    : token.UserID,
+		Email : token.Email,
+	})
+	if err != nil{
+		if errors.Is(err , sql.ErrNoRows){
+			helper.RespondWithError(w , http.StatusBadRequest , "Verification token is invalid or has expired.")
+		}else {
+			helper.RespondWithError(w , http.StatusInternalServerError , "An error occurred while processing your request.")
+		}
+		return
+	}
+	helper.RespondWithJSON(w , http.StatusOK , model.DatabaseUserToUser(user))
+}
+
+// ResendVerificationHandler sends a new verification email to the authenticated user.
+func (h *Handler) ResendVerificationHandler(w http.ResponseWriter , r *http.Request , jwtUserID uuid.UUID){
+	if r.Method != http.MethodPost{
+		helper.RespondWithError(w,http.StatusMethodNotAllowed , "Only POST requests are supported.")
+		return
+	}
+
+	user , err := h.Cfg.Db.FindUserByID(r.Context() , jwtUserID)
+	if err != nil{
+		helper.RespondWithError(w,http.StatusUnauthorized ,  "Unauthorized: Invalid credentials.")
+		return
+	}
+	if user.EmailVerifiedAt.Valid{
+		helper.RespondWithError(w , http.StatusConflict , "Email is already verified.")
+		return
+	}
+
+	err = h.sendVerificationEmail(r.Context() , user)
+	if err != nil{
+		helper.RespondWithError(w , http.StatusInternalServerError , "Unable to send verification email.")
+		return
+	}
+	helper.RespondWithJSON(w , http.StatusNoContent , nil)
+}
+
+// sendVerificationEmail creates a single-use verification token for the user's current email and mails it.
+func (h *Handler) sendVerificationEmail(ctx context.Context , user database.User) error{
+	token , err := auth.MakeRefreshToken()
+	if err != nil{
+		return err
+	}
+
+	// The expiry is set by the database, whose clock the consume query compares it with.
+	_ , err = h.Cfg.Db.CreateEmailVerificationToken(ctx , database.CreateEmailVerificationTokenParams{
+		TokenHash  : auth.HashToken(token),
+		UserID     : user.ID,
+		Email      : user.Email,
+		TtlSeconds : h.Cfg.EmailVerificationTTL.Seconds(),
+	})
+	if err != nil{
+		return err
+	}
+
+	link := fmt.Sprintf("%s/verify-email?token=%s" , h.Cfg.BaseURL , url.QueryEscape(token))
+	return h.Cfg.Mailer.Send(ctx , mailer.Message{
+		To      : user.Email,
+		Subject : "Confirm your email address",
+		Body    : fmt.Sprintf("Open this link to confirm your email address:\n\n%s\n\nOr send this token to POST /api/users/verify:\n\n%s\n\nThe link expires in %s." , link , token , h.Cfg.EmailVerificationTTL),
+	})
+}
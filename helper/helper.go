@@ -2,6 +2,7 @@ package helper
 
 import (
 	"encoding/json"
+	"net"
 	"net/http"
 )
 func RespondWithJSON(w http.ResponseWriter, code int, payload interface{}) error{
@@ -27,3 +28,12 @@ func RespondWithError(w http.ResponseWriter, code int, msg string) error{
 	return RespondWithJSON(w , code , map[string]string{"error":msg})
 
 }
+
+// ClientIP returns the IP address of the client that sent the request.
+func ClientIP(r *http.Request) string{
+	host , _ , err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil{
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -2,59 +2,13 @@ package auth
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"errors"
 	"net/http"
 	"strings"
-	"time"
-
-	"github.com/golang-jwt/jwt/v5"
-	"github.com/google/uuid"
-	"golang.org/x/crypto/bcrypt"
 )
 
-func HashPassword(password string) (string , error){
-	hash , err := bcrypt.GenerateFromPassword([]byte(password) , 10)
-	if err != nil {
-		return "" , err
-	}
-	return string(hash) , nil
-}
-
-func CheckPasswordHash(hashedPassword , password string) error{
-		return bcrypt.CompareHashAndPassword([]byte(hashedPassword) , []byte(password))
-}
-func MakeJWT(userID uuid.UUID, tokenSecret string) (string, error){
-	claims := jwt.RegisteredClaims{
-		Issuer: "chirpy", 
-		IssuedAt: jwt.NewNumericDate(time.Now()),
-		ExpiresAt: jwt.NewNumericDate(time.Now().Add(1 * time.Hour)),
-		Subject: userID.String(),
-	}	
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256 , claims)
-	return token.SignedString([]byte(tokenSecret))
-}
-
-func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error){
-	parsedToken , err := jwt.ParseWithClaims(tokenString , & jwt.RegisteredClaims{} , func(token *jwt.Token)(interface{} , error){
-		return []byte(tokenSecret) , nil
-	})
-	if err != nil {
-		return uuid.UUID{} , err
-	}
-	claims , ok := parsedToken.Claims.(*jwt.RegisteredClaims)
-	if !ok || !parsedToken.Valid {
-		return uuid.UUID{}, errors.New("invalid token")
-	}
-
-	uuidStr , err := claims.GetSubject();
-	id , err:= uuid.Parse(uuidStr)
-	if err != nil {
-		return uuid.UUID{} , err
-	}
-	return id , nil 
-}
-
 func GetBearerToken(headers http.Header) (string, error){
 	authHeader := headers.Get("Authorization")
 
@@ -78,6 +32,13 @@ func MakeRefreshToken() (string, error){
 	return hex.EncodeToString(randNum) , nil
 }
 
+// HashToken returns the SHA-256 digest of a single-use token. Only the digest is
+// stored so a leaked table can't be used to redeem tokens.
+func HashToken(token string) string{
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
 func GetAPIKey(headers http.Header) (string, error){
 	authHeader := headers.Get("Authorization")
 
@@ -0,0 +1,107 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// Claims are the claims carried by access tokens. Besides the registered
+// claims they describe the user so handlers don't need a database lookup.
+type Claims struct {
+	jwt.RegisteredClaims
+	IsPremium     bool     `json:"is_premium"`
+	EmailVerified bool     `json:"email_verified"`
+	Roles         []string `json:"roles,omitempty"`
+}
+
+// UserID returns the subject of the token as a user ID.
+func (c *Claims) UserID() (uuid.UUID, error) {
+	return uuid.Parse(c.Subject)
+}
+
+// UserClaims are the user specific claims added to a new access token.
+type UserClaims struct {
+	IsPremium     bool
+	EmailVerified bool
+	Roles         []string
+}
+
+// JWTManager issues and validates access tokens.
+type JWTManager struct {
+	Keys      *KeySet
+	Issuer    string
+	Audience  string // optional; tokens and validation skip it when empty
+	AccessTTL time.Duration
+	Leeway    time.Duration // clock skew allowed when checking exp, nbf and iat
+}
+
+func (m *JWTManager) MakeJWT(userID uuid.UUID, user UserClaims) (string, error) {
+	signingKey := m.Keys.Active()
+	if signingKey == nil {
+		return "", errors.New("no active JWT signing key")
+	}
+
+	now := time.Now()
+	claims := Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    m.Issuer,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(m.AccessTTL)),
+			Subject:   userID.String(),
+		},
+		IsPremium:     user.IsPremium,
+		EmailVerified: user.EmailVerified,
+		Roles:         user.Roles,
+	}
+	if m.Audience != "" {
+		claims.Audience = jwt.ClaimStrings{m.Audience}
+	}
+
+	token := jwt.NewWithClaims(signingKey.Method, claims)
+	if signingKey.ID != "" {
+		token.Header["kid"] = signingKey.ID
+	}
+	return token.SignedString(signingKey.PrivateKey)
+}
+
+// ValidateJWT checks the signature, expiry, issuer and audience of the token and returns its claims.
+func (m *JWTManager) ValidateJWT(tokenString string) (*Claims, error) {
+	options := []jwt.ParserOption{
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuer(m.Issuer),
+		jwt.WithLeeway(m.Leeway),
+	}
+	if m.Audience != "" {
+		options = append(options, jwt.WithAudience(m.Audience))
+	}
+
+	parsedToken, err := jwt.ParseWithClaims(tokenString, &Claims{}, m.Keys.Lookup, options...)
+	if err != nil {
+		return nil, err
+	}
+	claims, ok := parsedToken.Claims.(*Claims)
+	if !ok || !parsedToken.Valid {
+		return nil, errors.New("invalid token")
+	}
+	if _, err := claims.UserID(); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+type claimsContextKey struct{}
+
+// WithClaims returns a copy of ctx that carries the validated token claims.
+func WithClaims(ctx context.Context, claims *Claims) context.Context {
+	return context.WithValue(ctx, claimsContextKey{}, claims)
+}
+
+// ClaimsFromContext returns the claims stored by WithClaims, if any.
+func ClaimsFromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(claimsContextKey{}).(*Claims)
+	return claims, ok
+}
@@ -0,0 +1,197 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrMismatchedHash    = errors.New("password does not match hash")
+	ErrUnknownHashFormat = errors.New("unknown password hash format")
+)
+
+// Hasher hashes passwords with one algorithm. The encoded hash records the
+// algorithm and its parameters so it can be verified after the defaults change.
+type Hasher interface {
+	// Hash returns the encoded hash of the password.
+	Hash(password string) (string, error)
+	// Compare returns nil if the password matches the encoded hash.
+	Compare(encodedHash, password string) error
+	// Recognizes reports whether the encoded hash was produced by this algorithm.
+	Recognizes(encodedHash string) bool
+	// NeedsRehash reports whether the encoded hash uses weaker parameters than the hasher.
+	NeedsRehash(encodedHash string) bool
+}
+
+// PasswordHasher hashes new passwords with its default hasher and verifies
+// hashes produced by any of its known hashers.
+type PasswordHasher struct {
+	Default Hasher
+	Hashers []Hasher
+}
+
+func NewPasswordHasher(defaultHasher Hasher, others ...Hasher) *PasswordHasher {
+	return &PasswordHasher{
+		Default: defaultHasher,
+		Hashers: append([]Hasher{defaultHasher}, others...),
+	}
+}
+
+// NewPasswordHasherByName builds a PasswordHasher that uses the named algorithm
+// ("bcrypt" or "argon2id") for new hashes and still verifies the other one.
+func NewPasswordHasherByName(name string, bcryptHasher *BcryptHasher, argon2idHasher *Argon2idHasher) (*PasswordHasher, error) {
+	switch strings.ToLower(name) {
+	case "", "bcrypt":
+		return NewPasswordHasher(bcryptHasher, argon2idHasher), nil
+	case "argon2id":
+		return NewPasswordHasher(argon2idHasher, bcryptHasher), nil
+	}
+	return nil, fmt.Errorf("unsupported password hasher %q", name)
+}
+
+func (p *PasswordHasher) Hash(password string) (string, error) {
+	return p.Default.Hash(password)
+}
+
+// Verify checks the password against the encoded hash. On success it also
+// reports whether the hash should be replaced by one from the default hasher.
+func (p *PasswordHasher) Verify(encodedHash, password string) (bool, error) {
+	for _, hasher := range p.Hashers {
+		if !hasher.Recognizes(encodedHash) {
+			continue
+		}
+		if err := hasher.Compare(encodedHash, password); err != nil {
+			return false, err
+		}
+		return hasher != p.Default || hasher.NeedsRehash(encodedHash), nil
+	}
+	return false, ErrUnknownHashFormat
+}
+
+// BcryptHasher stores hashes in the standard $2a$<cost>$ format.
+type BcryptHasher struct {
+	Cost int
+}
+
+func (b *BcryptHasher) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), b.Cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+func (b *BcryptHasher) Compare(encodedHash, password string) error {
+	err := bcrypt.CompareHashAndPassword([]byte(encodedHash), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return ErrMismatchedHash
+	}
+	return err
+}
+
+func (b *BcryptHasher) Recognizes(encodedHash string) bool {
+	return strings.HasPrefix(encodedHash, "$2a$") ||
+		strings.HasPrefix(encodedHash, "$2b$") ||
+		strings.HasPrefix(encodedHash, "$2y$")
+}
+
+func (b *BcryptHasher) NeedsRehash(encodedHash string) bool {
+	cost, err := bcrypt.Cost([]byte(encodedHash))
+	return err != nil || cost < b.Cost
+}
+
+// Argon2idHasher stores hashes in the PHC string format:
+// $argon2id$v=19$m=<memory>,t=<iterations>,p=<parallelism>$<salt>$<key>
+type Argon2idHasher struct {
+	Memory      uint32 // KiB
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+type argon2idParams struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+	salt        []byte
+	key         []byte
+}
+
+func (a *Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, a.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, a.Iterations, a.Memory, a.Parallelism, a.KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, a.Memory, a.Iterations, a.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+func (a *Argon2idHasher) Compare(encodedHash, password string) error {
+	params, err := decodeArgon2id(encodedHash)
+	if err != nil {
+		return err
+	}
+	key := argon2.IDKey([]byte(password), params.salt, params.iterations, params.memory, params.parallelism, uint32(len(params.key)))
+	if subtle.ConstantTimeCompare(key, params.key) != 1 {
+		return ErrMismatchedHash
+	}
+	return nil
+}
+
+func (a *Argon2idHasher) Recognizes(encodedHash string) bool {
+	return strings.HasPrefix(encodedHash, "$argon2id$")
+}
+
+func (a *Argon2idHasher) NeedsRehash(encodedHash string) bool {
+	params, err := decodeArgon2id(encodedHash)
+	if err != nil {
+		return true
+	}
+	return params.memory < a.Memory ||
+		params.iterations < a.Iterations ||
+		params.parallelism < a.Parallelism ||
+		uint32(len(params.salt)) < a.SaltLength ||
+		uint32(len(params.key)) < a.KeyLength
+}
+
+func decodeArgon2id(encodedHash string) (argon2idParams, error) {
+	var params argon2idParams
+
+	parts := strings.Split(encodedHash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return params, ErrUnknownHashFormat
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return params, ErrUnknownHashFormat
+	}
+	if version != argon2.Version {
+		return params, fmt.Errorf("unsupported argon2 version %d", version)
+	}
+
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.iterations, &params.parallelism); err != nil {
+		return params, ErrUnknownHashFormat
+	}
+
+	var err error
+	if params.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return params, ErrUnknownHashFormat
+	}
+	if params.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil {
+		return params, ErrUnknownHashFormat
+	}
+	return params, nil
+}
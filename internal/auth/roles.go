@@ -0,0 +1,40 @@
+package auth
+
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+type Permission string
+
+const (
+	PermissionDeleteAnyPost Permission = "posts:delete_any"
+	PermissionManageRoles   Permission = "users:manage_roles"
+	PermissionResetDatabase Permission = "admin:reset"
+)
+
+// rolePermissions lists what each role may do on top of what every user can do.
+var rolePermissions = map[string][]Permission{
+	RoleUser:      {},
+	RoleModerator: {PermissionDeleteAnyPost},
+	RoleAdmin:     {PermissionDeleteAnyPost, PermissionManageRoles, PermissionResetDatabase},
+}
+
+// ValidRole reports whether role is one of the known roles.
+func ValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// HasPermission reports whether any of the roles grants the permission.
+func HasPermission(roles []string, permission Permission) bool {
+	for _, role := range roles {
+		for _, p := range rolePermissions[role] {
+			if p == permission {
+				return true
+			}
+		}
+	}
+	return false
+}
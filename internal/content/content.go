@@ -0,0 +1,179 @@
+// Package content cleans and validates user submitted text such as post bodies.
+package content
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Violation describes one reason a text was rejected.
+type Violation struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ValidationError is returned when a text breaks one or more rules.
+type ValidationError struct {
+	Violations []Violation
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		messages = append(messages, v.Message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Step is one stage of a Pipeline. It may rewrite the text, reject it, or both.
+type Step interface {
+	Apply(text string) (string, []Violation)
+}
+
+// StepFunc lets a plain function be used as a Step.
+type StepFunc func(text string) (string, []Violation)
+
+func (f StepFunc) Apply(text string) (string, []Violation) {
+	return f(text)
+}
+
+// Pipeline runs its steps in order, each on the output of the previous one.
+type Pipeline struct {
+	steps []Step
+}
+
+func NewPipeline(steps ...Step) *Pipeline {
+	return &Pipeline{steps: steps}
+}
+
+// Use appends more steps to the pipeline.
+func (p *Pipeline) Use(steps ...Step) *Pipeline {
+	p.steps = append(p.steps, steps...)
+	return p
+}
+
+// Process returns the cleaned text, or a *ValidationError listing every violation found.
+func (p *Pipeline) Process(text string) (string, error) {
+	var violations []Violation
+	for _, step := range p.steps {
+		var found []Violation
+		text, found = step.Apply(text)
+		violations = append(violations, found...)
+	}
+	if len(violations) > 0 {
+		return "", &ValidationError{Violations: violations}
+	}
+	return text, nil
+}
+
+// Normalize rejects invalid UTF-8, applies NFKC normalization so look-alike
+// characters compare equal, drops control characters and trims surrounding space.
+func Normalize() Step {
+	return StepFunc(func(text string) (string, []Violation) {
+		if !utf8.ValidString(text) {
+			return text, []Violation{{Code: "invalid_encoding", Message: "Text must be valid UTF-8."}}
+		}
+		text = norm.NFKC.String(text)
+		text = strings.Map(func(r rune) rune {
+			if unicode.IsControl(r) && r != '\n' && r != '\t' {
+				return -1
+			}
+			return r
+		}, text)
+		return strings.TrimSpace(text), nil
+	})
+}
+
+// NotEmpty rejects text with nothing left in it.
+func NotEmpty() Step {
+	return StepFunc(func(text string) (string, []Violation) {
+		if text == "" {
+			return text, []Violation{{Code: "empty", Message: "Text must not be empty."}}
+		}
+		return text, nil
+	})
+}
+
+// MaxLength rejects text longer than max characters.
+func MaxLength(max int) Step {
+	return StepFunc(func(text string) (string, []Violation) {
+		if utf8.RuneCountInString(text) > max {
+			return text, []Violation{{
+				Code:    "too_long",
+				Message: "Text must be at most " + strconv.Itoa(max) + " characters long.",
+			}}
+		}
+		return text, nil
+	})
+}
+
+// MaskBannedWords replaces every whole-word, case-insensitive match of a banned word with "****".
+func MaskBannedWords(words []string) Step {
+	var normalized []string
+	for _, word := range words {
+		word = strings.TrimSpace(word)
+		if word != "" {
+			normalized = append(normalized, norm.NFKC.String(word))
+		}
+	}
+	if len(normalized) == 0 {
+		return StepFunc(func(text string) (string, []Violation) { return text, nil })
+	}
+
+	// Go takes the first alternative that matches, not the longest, so longer words go
+	// first. Otherwise "foo" would win over "foobar" and then fail the boundary check.
+	sort.SliceStable(normalized, func(i, j int) bool {
+		return utf8.RuneCountInString(normalized[i]) > utf8.RuneCountInString(normalized[j])
+	})
+	quoted := make([]string, len(normalized))
+	for i, word := range normalized {
+		quoted[i] = regexp.QuoteMeta(word)
+	}
+
+	pattern := regexp.MustCompile(`(?i)` + strings.Join(quoted, "|"))
+	return StepFunc(func(text string) (string, []Violation) {
+		var b strings.Builder
+		last, pos := 0, 0
+		for pos < len(text) {
+			match := pattern.FindStringIndex(text[pos:])
+			if match == nil {
+				break
+			}
+			start, end := pos+match[0], pos+match[1]
+			if !isWordBoundary(text, start, end) {
+				// A later word may still start inside a match that isn't a whole word.
+				_, size := utf8.DecodeRuneInString(text[start:])
+				pos = start + size
+				continue
+			}
+			b.WriteString(text[last:start])
+			b.WriteString("****")
+			last, pos = end, end
+		}
+		b.WriteString(text[last:])
+		return b.String(), nil
+	})
+}
+
+// isWordBoundary reports whether text[start:end] is not glued to letters or digits on either side.
+func isWordBoundary(text string, start, end int) bool {
+	if start > 0 {
+		r, _ := utf8.DecodeLastRuneInString(text[:start])
+		if unicode.IsLetter(r) || unicode.IsNumber(r) {
+			return false
+		}
+	}
+	if end < len(text) {
+		r, _ := utf8.DecodeRuneInString(text[end:])
+		if unicode.IsLetter(r) || unicode.IsNumber(r) {
+			return false
+		}
+	}
+	return true
+}
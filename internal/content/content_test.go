@@ -0,0 +1,42 @@
+package content
+
+import "testing"
+
+func TestMaskBannedWords(t *testing.T) {
+	tests := []struct {
+		name  string
+		words []string
+		input string
+		want  string
+	}{
+		{"whole word", []string{"foo"}, "say foo now", "say **** now"},
+		{"inside another word", []string{"foo"}, "food and snafoo", "food and snafoo"},
+		{"longer word after its prefix", []string{"foo", "foobar"}, "foobar", "****"},
+		{"longer word before its prefix", []string{"foobar", "foo"}, "foo foobar", "**** ****"},
+		{"prefix glued to other letters", []string{"foo", "foobar"}, "foobarbaz", "foobarbaz"},
+		{"word inside a rejected match", []string{"foo bar", "bar"}, "xfoo bar", "xfoo ****"},
+		{"punctuation is a boundary", []string{"foo", "foobar"}, "foo, foobar!", "****, ****!"},
+		{"non-ASCII letters are not a boundary", []string{"foo"}, "éfoo fooé", "éfoo fooé"},
+		{"digits are not a boundary", []string{"foo"}, "foo1 2foo", "foo1 2foo"},
+		{"case", []string{"Foo"}, "FOO foo fOo", "**** **** ****"},
+		{"case with overlap", []string{"foo", "FOOBAR"}, "FooBar", "****"},
+		{"fullwidth text", []string{"foo"}, "ｆｏｏ", "****"},
+		{"fullwidth word", []string{"ｆｏｏｂａｒ"}, "foobar", "****"},
+		{"ligature", []string{"fish"}, "ﬁsh", "****"},
+		{"no words", nil, "foo", "foo"},
+		{"blank words are ignored", []string{"", "  "}, "foo", "foo"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pipeline := NewPipeline(Normalize(), MaskBannedWords(tt.words))
+			got, err := pipeline.Process(tt.input)
+			if err != nil {
+				t.Fatalf("Process(%q) returned error: %v", tt.input, err)
+			}
+			if got != tt.want {
+				t.Errorf("Process(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
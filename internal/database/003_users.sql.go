@@ -7,6 +7,7 @@ package database
 
 import (
 	"context"
+	"database/sql"
 
 	"github.com/google/uuid"
 )
@@ -14,7 +15,7 @@ import (
 const createUser = `-- name: CreateUser :one
 INSERT INTO users (id, created_at, updated_at, email , hashed_password)
 VALUES (gen_random_uuid() , NOW() , NOW() , $1 , $2)
-RETURNING id, created_at, updated_at, email, hashed_password, is_premium
+RETURNING id, created_at, updated_at, email, hashed_password, is_premium, role, email_verified_at, handle, display_name, bio, avatar_url, avatar_key
 `
 
 type CreateUserParams struct {
@@ -32,6 +33,13 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsPremium,
+		&i.Role,
+		&i.EmailVerifiedAt,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.AvatarKey,
 	)
 	return i, err
 }
@@ -47,9 +55,10 @@ func (q *Queries) DeleteAllUsers(ctx context.Context) error {
 
 const editUserByID = `-- name: EditUserByID :one
 UPDATE users 
-SET email = $2 , hashed_password = $3
+SET email = $2 , hashed_password = $3,
+  email_verified_at = CASE WHEN email = $2 THEN email_verified_at ELSE NULL END
 where id = $1
-RETURNING id, created_at, updated_at, email, hashed_password, is_premium
+RETURNING id, created_at, updated_at, email, hashed_password, is_premium, role, email_verified_at, handle, display_name, bio, avatar_url, avatar_key
 `
 
 type EditUserByIDParams struct {
@@ -68,12 +77,19 @@ func (q *Queries) EditUserByID(ctx context.Context, arg EditUserByIDParams) (Use
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsPremium,
+		&i.Role,
+		&i.EmailVerifiedAt,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.AvatarKey,
 	)
 	return i, err
 }
 
 const findUserByEmail = `-- name: FindUserByEmail :one
-SELECT id, created_at, updated_at, email, hashed_password, is_premium from users
+SELECT id, created_at, updated_at, email, hashed_password, is_premium, role, email_verified_at, handle, display_name, bio, avatar_url, avatar_key from users
 where email = $1
 `
 
@@ -87,12 +103,45 @@ func (q *Queries) FindUserByEmail(ctx context.Context, email string) (User, erro
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsPremium,
+		&i.Role,
+		&i.EmailVerifiedAt,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.AvatarKey,
+	)
+	return i, err
+}
+
+const findUserByHandle = `-- name: FindUserByHandle :one
+SELECT id, created_at, updated_at, email, hashed_password, is_premium, role, email_verified_at, handle, display_name, bio, avatar_url, avatar_key from users
+where handle = $1
+`
+
+func (q *Queries) FindUserByHandle(ctx context.Context, handle sql.NullString) (User, error) {
+	row := q.db.QueryRowContext(ctx, findUserByHandle, handle)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsPremium,
+		&i.Role,
+		&i.EmailVerifiedAt,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.AvatarKey,
 	)
 	return i, err
 }
 
 const findUserByID = `-- name: FindUserByID :one
-SELECT id, created_at, updated_at, email, hashed_password, is_premium from users
+SELECT id, created_at, updated_at, email, hashed_password, is_premium, role, email_verified_at, handle, display_name, bio, avatar_url, avatar_key from users
 where id = $1
 `
 
@@ -106,6 +155,138 @@ func (q *Queries) FindUserByID(ctx context.Context, id uuid.UUID) (User, error)
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsPremium,
+		&i.Role,
+		&i.EmailVerifiedAt,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.AvatarKey,
+	)
+	return i, err
+}
+
+const setUserAvatar = `-- name: SetUserAvatar :one
+UPDATE users
+SET avatar_url = $2, avatar_key = $3, updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, email, hashed_password, is_premium, role, email_verified_at, handle, display_name, bio, avatar_url, avatar_key
+`
+
+type SetUserAvatarParams struct {
+	ID        uuid.UUID
+	AvatarUrl string
+	AvatarKey string
+}
+
+func (q *Queries) SetUserAvatar(ctx context.Context, arg SetUserAvatarParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, setUserAvatar, arg.ID, arg.AvatarUrl, arg.AvatarKey)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsPremium,
+		&i.Role,
+		&i.EmailVerifiedAt,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.AvatarKey,
+	)
+	return i, err
+}
+
+const setUserRole = `-- name: SetUserRole :one
+UPDATE users
+SET role = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, email, hashed_password, is_premium, role, email_verified_at, handle, display_name, bio, avatar_url, avatar_key
+`
+
+type SetUserRoleParams struct {
+	ID   uuid.UUID
+	Role string
+}
+
+func (q *Queries) SetUserRole(ctx context.Context, arg SetUserRoleParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, setUserRole, arg.ID, arg.Role)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsPremium,
+		&i.Role,
+		&i.EmailVerifiedAt,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.AvatarKey,
+	)
+	return i, err
+}
+
+const updateUserPassword = `-- name: UpdateUserPassword :exec
+UPDATE users
+SET hashed_password = $2, updated_at = NOW()
+WHERE id = $1
+`
+
+type UpdateUserPasswordParams struct {
+	ID             uuid.UUID
+	HashedPassword string
+}
+
+func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) error {
+	_, err := q.db.ExecContext(ctx, updateUserPassword, arg.ID, arg.HashedPassword)
+	return err
+}
+
+const updateUserProfile = `-- name: UpdateUserProfile :one
+UPDATE users
+SET handle = $2, display_name = $3, bio = $4, avatar_url = $5, updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, email, hashed_password, is_premium, role, email_verified_at, handle, display_name, bio, avatar_url, avatar_key
+`
+
+type UpdateUserProfileParams struct {
+	ID          uuid.UUID
+	Handle      sql.NullString
+	DisplayName string
+	Bio         string
+	AvatarUrl   string
+}
+
+func (q *Queries) UpdateUserProfile(ctx context.Context, arg UpdateUserProfileParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserProfile,
+		arg.ID,
+		arg.Handle,
+		arg.DisplayName,
+		arg.Bio,
+		arg.AvatarUrl,
+	)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsPremium,
+		&i.Role,
+		&i.EmailVerifiedAt,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.AvatarKey,
 	)
 	return i, err
 }
@@ -114,7 +295,7 @@ const upgradeUserByID = `-- name: UpgradeUserByID :one
 UPDATE users
 SET is_premium = TRUE
 WHERE id = $1
-RETURNING id, created_at, updated_at, email, hashed_password, is_premium
+RETURNING id, created_at, updated_at, email, hashed_password, is_premium, role, email_verified_at, handle, display_name, bio, avatar_url, avatar_key
 `
 
 func (q *Queries) UpgradeUserByID(ctx context.Context, id uuid.UUID) (User, error) {
@@ -127,6 +308,13 @@ func (q *Queries) UpgradeUserByID(ctx context.Context, id uuid.UUID) (User, erro
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsPremium,
+		&i.Role,
+		&i.EmailVerifiedAt,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.AvatarKey,
 	)
 	return i, err
 }
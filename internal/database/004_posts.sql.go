@@ -7,23 +7,27 @@ package database
 
 import (
 	"context"
+	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const createPost = `-- name: CreatePost :one
-INSERT INTO posts(id, created_at, updated_at, body , user_id)
-VALUES (gen_random_uuid() , NOW() , NOW() , $1 , $2)
-RETURNING id, created_at, updated_at, body, user_id
+INSERT INTO posts(id, created_at, updated_at, body , user_id , parent_id)
+VALUES (gen_random_uuid() , NOW() , NOW() , $1 , $2 , $3)
+RETURNING id, created_at, updated_at, body, user_id, deleted_at, deleted_by, parent_id
 `
 
 type CreatePostParams struct {
-	Body   string
-	UserID uuid.UUID
+	Body     string
+	UserID   uuid.UUID
+	ParentID uuid.NullUUID
 }
 
 func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, error) {
-	row := q.db.QueryRowContext(ctx, createPost, arg.Body, arg.UserID)
+	row := q.db.QueryRowContext(ctx, createPost, arg.Body, arg.UserID, arg.ParentID)
 	var i Post
 	err := row.Scan(
 		&i.ID,
@@ -31,18 +35,27 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.DeletedAt,
+		&i.DeletedBy,
+		&i.ParentID,
 	)
 	return i, err
 }
 
 const deletePost = `-- name: DeletePost :one
-DELETE FROM posts
-WHERE id = $1
-RETURNING id, created_at, updated_at, body, user_id
+UPDATE posts
+SET deleted_at = NOW(), deleted_by = $2
+WHERE id = $1 AND deleted_at IS NULL
+RETURNING id, created_at, updated_at, body, user_id, deleted_at, deleted_by, parent_id
 `
 
-func (q *Queries) DeletePost(ctx context.Context, id uuid.UUID) (Post, error) {
-	row := q.db.QueryRowContext(ctx, deletePost, id)
+type DeletePostParams struct {
+	ID        uuid.UUID
+	DeletedBy uuid.NullUUID
+}
+
+func (q *Queries) DeletePost(ctx context.Context, arg DeletePostParams) (Post, error) {
+	row := q.db.QueryRowContext(ctx, deletePost, arg.ID, arg.DeletedBy)
 	var i Post
 	err := row.Scan(
 		&i.ID,
@@ -50,18 +63,81 @@ func (q *Queries) DeletePost(ctx context.Context, id uuid.UUID) (Post, error) {
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.DeletedAt,
+		&i.DeletedBy,
+		&i.ParentID,
 	)
 	return i, err
 }
 
-const getAllPosts = `-- name: GetAllPosts :many
-SELECT id, created_at, updated_at, body, user_id 
+const getDeletedPost = `-- name: GetDeletedPost :one
+SELECT id, created_at, updated_at, body, user_id, deleted_at, deleted_by, parent_id
 FROM posts
-ORDER BY created_at ASC
+WHERE posts.id = $1 AND posts.deleted_at IS NOT NULL
 `
 
-func (q *Queries) GetAllPosts(ctx context.Context) ([]Post, error) {
-	rows, err := q.db.QueryContext(ctx, getAllPosts)
+func (q *Queries) GetDeletedPost(ctx context.Context, id uuid.UUID) (Post, error) {
+	row := q.db.QueryRowContext(ctx, getDeletedPost, id)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.DeletedAt,
+		&i.DeletedBy,
+		&i.ParentID,
+	)
+	return i, err
+}
+
+const getPost = `-- name: GetPost :one
+SELECT id, created_at, updated_at, body, user_id, deleted_at, deleted_by, parent_id
+FROM posts
+where posts.id = $1 AND posts.deleted_at IS NULL
+`
+
+func (q *Queries) GetPost(ctx context.Context, id uuid.UUID) (Post, error) {
+	row := q.db.QueryRowContext(ctx, getPost, id)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.DeletedAt,
+		&i.DeletedBy,
+		&i.ParentID,
+	)
+	return i, err
+}
+
+const getPostsByAuthorIDs = `-- name: GetPostsByAuthorIDs :many
+SELECT id, created_at, updated_at, body, user_id, deleted_at, deleted_by, parent_id
+FROM posts
+WHERE posts.user_id = ANY($1::uuid[]) AND posts.deleted_at IS NULL
+  AND ($2::timestamp IS NULL
+    OR (posts.created_at, posts.id) < ($2, $3::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT $4
+`
+
+type GetPostsByAuthorIDsParams struct {
+	AuthorIds       []uuid.UUID
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	Limit           int32
+}
+
+func (q *Queries) GetPostsByAuthorIDs(ctx context.Context, arg GetPostsByAuthorIDsParams) ([]Post, error) {
+	rows, err := q.db.QueryContext(ctx, getPostsByAuthorIDs,
+		pq.Array(arg.AuthorIds),
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.Limit,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -75,6 +151,9 @@ func (q *Queries) GetAllPosts(ctx context.Context) ([]Post, error) {
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.DeletedAt,
+			&i.DeletedBy,
+			&i.ParentID,
 		); err != nil {
 			return nil, err
 		}
@@ -89,14 +168,144 @@ func (q *Queries) GetAllPosts(ctx context.Context) ([]Post, error) {
 	return items, nil
 }
 
-const getPost = `-- name: GetPost :one
-SELECT id, created_at, updated_at, body, user_id 
+const listPostsAsc = `-- name: ListPostsAsc :many
+SELECT id, created_at, updated_at, body, user_id, deleted_at, deleted_by, parent_id
 FROM posts
-where posts.id = $1
+WHERE posts.deleted_at IS NULL
+  AND ($1::uuid IS NULL OR posts.user_id = $1)
+  AND ($2::timestamp IS NULL
+    OR (posts.created_at, posts.id) > ($2, $3::uuid))
+ORDER BY created_at ASC, id ASC
+LIMIT $4
 `
 
-func (q *Queries) GetPost(ctx context.Context, id uuid.UUID) (Post, error) {
-	row := q.db.QueryRowContext(ctx, getPost, id)
+type ListPostsAscParams struct {
+	AuthorID        uuid.NullUUID
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	Limit           int32
+}
+
+func (q *Queries) ListPostsAsc(ctx context.Context, arg ListPostsAscParams) ([]Post, error) {
+	rows, err := q.db.QueryContext(ctx, listPostsAsc,
+		arg.AuthorID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Post
+	for rows.Next() {
+		var i Post
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.DeletedAt,
+			&i.DeletedBy,
+			&i.ParentID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPostsDesc = `-- name: ListPostsDesc :many
+SELECT id, created_at, updated_at, body, user_id, deleted_at, deleted_by, parent_id
+FROM posts
+WHERE posts.deleted_at IS NULL
+  AND ($1::uuid IS NULL OR posts.user_id = $1)
+  AND ($2::timestamp IS NULL
+    OR (posts.created_at, posts.id) < ($2, $3::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT $4
+`
+
+type ListPostsDescParams struct {
+	AuthorID        uuid.NullUUID
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	Limit           int32
+}
+
+func (q *Queries) ListPostsDesc(ctx context.Context, arg ListPostsDescParams) ([]Post, error) {
+	rows, err := q.db.QueryContext(ctx, listPostsDesc,
+		arg.AuthorID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Post
+	for rows.Next() {
+		var i Post
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.DeletedAt,
+			&i.DeletedBy,
+			&i.ParentID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const purgeDeletedPosts = `-- name: PurgeDeletedPosts :execrows
+DELETE FROM posts
+WHERE deleted_at < NOW() - make_interval(secs => $1::float8)
+  AND NOT EXISTS (SELECT 1 FROM posts AS replies WHERE replies.parent_id = posts.id)
+`
+
+func (q *Queries) PurgeDeletedPosts(ctx context.Context, restoreWindowSeconds float64) (int64, error) {
+	result, err := q.db.ExecContext(ctx, purgeDeletedPosts, restoreWindowSeconds)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const restorePost = `-- name: RestorePost :one
+UPDATE posts
+SET deleted_at = NULL, deleted_by = NULL
+WHERE id = $1 AND deleted_at >= NOW() - make_interval(secs => $2::float8)
+RETURNING id, created_at, updated_at, body, user_id, deleted_at, deleted_by, parent_id
+`
+
+type RestorePostParams struct {
+	ID                   uuid.UUID
+	RestoreWindowSeconds float64
+}
+
+func (q *Queries) RestorePost(ctx context.Context, arg RestorePostParams) (Post, error) {
+	row := q.db.QueryRowContext(ctx, restorePost, arg.ID, arg.RestoreWindowSeconds)
 	var i Post
 	err := row.Scan(
 		&i.ID,
@@ -104,32 +313,74 @@ func (q *Queries) GetPost(ctx context.Context, id uuid.UUID) (Post, error) {
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.DeletedAt,
+		&i.DeletedBy,
+		&i.ParentID,
 	)
 	return i, err
 }
 
-const getPostsByAuthorID = `-- name: GetPostsByAuthorID :many
-SELECT id, created_at, updated_at, body, user_id
-FROM posts
-WHERE posts.user_id = $1
-ORDER BY created_at ASC
+const searchPosts = `-- name: SearchPosts :many
+SELECT posts.id, posts.created_at, posts.updated_at, posts.body, posts.user_id, posts.parent_id,
+  ts_rank(posts.search_vector, query)::real AS rank,
+  ts_headline('english',
+    replace(replace(replace(posts.body, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'),
+    query, 'StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MinWords=5, MaxWords=20')::text AS snippet
+FROM posts, websearch_to_tsquery('english', $1) AS query
+WHERE posts.search_vector @@ query
+  AND posts.deleted_at IS NULL
+  AND ($2::uuid IS NULL OR posts.user_id = $2)
+  AND ($3::timestamp IS NULL OR posts.created_at >= $3)
+  AND ($4::timestamp IS NULL OR posts.created_at < $4)
+ORDER BY rank DESC, posts.created_at DESC, posts.id DESC
+LIMIT $5 OFFSET $6
 `
 
-func (q *Queries) GetPostsByAuthorID(ctx context.Context, userID uuid.UUID) ([]Post, error) {
-	rows, err := q.db.QueryContext(ctx, getPostsByAuthorID, userID)
+type SearchPostsParams struct {
+	Query    string
+	AuthorID uuid.NullUUID
+	Since    sql.NullTime
+	Until    sql.NullTime
+	Limit    int32
+	Offset   int32
+}
+
+type SearchPostsRow struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Body      string
+	UserID    uuid.UUID
+	ParentID  uuid.NullUUID
+	Rank      float32
+	Snippet   string
+}
+
+func (q *Queries) SearchPosts(ctx context.Context, arg SearchPostsParams) ([]SearchPostsRow, error) {
+	rows, err := q.db.QueryContext(ctx, searchPosts,
+		arg.Query,
+		arg.AuthorID,
+		arg.Since,
+		arg.Until,
+		arg.Limit,
+		arg.Offset,
+	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Post
+	var items []SearchPostsRow
 	for rows.Next() {
-		var i Post
+		var i SearchPostsRow
 		if err := rows.Scan(
 			&i.ID,
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.ParentID,
+			&i.Rank,
+			&i.Snippet,
 		); err != nil {
 			return nil, err
 		}
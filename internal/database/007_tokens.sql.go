@@ -14,18 +14,19 @@ import (
 )
 
 const generateRefreshToken = `-- name: GenerateRefreshToken :one
-INSERT INTO refresh_tokens(token, created_at, updated_at, user_id , expires_at)
-VALUES ($1 , NOW() , NOW() , $2 , NOW() + INTERVAL '60 days')
-RETURNING token, created_at, updated_at, user_id, expires_at, revoked_at
+INSERT INTO refresh_tokens(token, created_at, updated_at, user_id , expires_at , family_id)
+VALUES ($1 , NOW() , NOW() , $2 , NOW() + INTERVAL '60 days' , $3)
+RETURNING token, created_at, updated_at, user_id, expires_at, revoked_at, family_id, replaced_by
 `
 
 type GenerateRefreshTokenParams struct {
-	Token  string
-	UserID uuid.UUID
+	Token    string
+	UserID   uuid.UUID
+	FamilyID uuid.UUID
 }
 
 func (q *Queries) GenerateRefreshToken(ctx context.Context, arg GenerateRefreshTokenParams) (RefreshToken, error) {
-	row := q.db.QueryRowContext(ctx, generateRefreshToken, arg.Token, arg.UserID)
+	row := q.db.QueryRowContext(ctx, generateRefreshToken, arg.Token, arg.UserID, arg.FamilyID)
 	var i RefreshToken
 	err := row.Scan(
 		&i.Token,
@@ -34,12 +35,14 @@ func (q *Queries) GenerateRefreshToken(ctx context.Context, arg GenerateRefreshT
 		&i.UserID,
 		&i.ExpiresAt,
 		&i.RevokedAt,
+		&i.FamilyID,
+		&i.ReplacedBy,
 	)
 	return i, err
 }
 
 const getRefreshToken = `-- name: GetRefreshToken :one
-SELECT token, created_at, updated_at, user_id, expires_at, revoked_at
+SELECT token, created_at, updated_at, user_id, expires_at, revoked_at, family_id, replaced_by
 FROM refresh_tokens
 WHERE token = $1
 `
@@ -54,6 +57,8 @@ func (q *Queries) GetRefreshToken(ctx context.Context, token string) (RefreshTok
 		&i.UserID,
 		&i.ExpiresAt,
 		&i.RevokedAt,
+		&i.FamilyID,
+		&i.ReplacedBy,
 	)
 	return i, err
 }
@@ -74,3 +79,33 @@ func (q *Queries) RevokeRefreshToken(ctx context.Context, arg RevokeRefreshToken
 	_, err := q.db.ExecContext(ctx, revokeRefreshToken, arg.RevokedAt, arg.UpdatedAt, arg.Token)
 	return err
 }
+
+const revokeRefreshTokenFamily = `-- name: RevokeRefreshTokenFamily :exec
+UPDATE refresh_tokens
+SET revoked_at = NOW(), updated_at = NOW()
+WHERE family_id = $1 AND revoked_at IS NULL
+`
+
+func (q *Queries) RevokeRefreshTokenFamily(ctx context.Context, familyID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, revokeRefreshTokenFamily, familyID)
+	return err
+}
+
+const rotateRefreshToken = `-- name: RotateRefreshToken :execrows
+UPDATE refresh_tokens
+SET revoked_at = NOW(), updated_at = NOW(), replaced_by = $2
+WHERE token = $1 AND revoked_at IS NULL
+`
+
+type RotateRefreshTokenParams struct {
+	Token      string
+	ReplacedBy sql.NullString
+}
+
+func (q *Queries) RotateRefreshToken(ctx context.Context, arg RotateRefreshTokenParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, rotateRefreshToken, arg.Token, arg.ReplacedBy)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
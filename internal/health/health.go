@@ -0,0 +1,123 @@
+// Package health runs the readiness checks behind /readyz.
+package health
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	StatusOK       = "ok"
+	StatusDegraded = "degraded"
+	StatusFailing  = "failing"
+	StatusDraining = "draining"
+)
+
+// CheckFunc returns an error when the dependency it checks is not usable, or an
+// error wrapped with Degraded when it is usable but worth a look.
+type CheckFunc func(ctx context.Context) error
+
+// Degraded marks err as a problem that is reported without failing readiness.
+func Degraded(err error) error {
+	return degradedError{err: err}
+}
+
+type degradedError struct {
+	err error
+}
+
+func (e degradedError) Error() string { return e.err.Error() }
+
+func (e degradedError) Unwrap() error { return e.err }
+
+// Checker holds the named readiness checks and whether the server is shutting down.
+type Checker struct {
+	// Timeout bounds each check, so one slow dependency can't stall the probe.
+	Timeout time.Duration
+
+	checks   []namedCheck
+	draining atomic.Bool
+}
+
+type namedCheck struct {
+	name string
+	fn   CheckFunc
+}
+
+// Report is the JSON body of a readiness response.
+type Report struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+// CheckResult is the outcome of one check. Errors are logged rather than
+// reported, since /readyz is public.
+type CheckResult struct {
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latency_ms"`
+}
+
+// NewChecker returns a Checker with no checks that gives each one up to timeout.
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{Timeout: timeout}
+}
+
+// Add registers a check. Checks must be added before the server starts.
+func (c *Checker) Add(name string, fn CheckFunc) {
+	c.checks = append(c.checks, namedCheck{name: name, fn: fn})
+}
+
+// SetDraining marks the server as shutting down, which fails every later report.
+func (c *Checker) SetDraining() {
+	c.draining.Store(true)
+}
+
+// Run runs every check concurrently and logs any errors. The report is ok only if
+// every check passed or was degraded and the server is not draining.
+func (c *Checker) Run(ctx context.Context) Report {
+	report := Report{Status: StatusOK, Checks: make(map[string]CheckResult, len(c.checks))}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, check := range c.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, c.Timeout)
+			defer cancel()
+
+			start := time.Now()
+			err := check.fn(checkCtx)
+			result := CheckResult{
+				Status:    StatusOK,
+				LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+			}
+			var degraded degradedError
+			switch {
+			case errors.As(err, &degraded):
+				result.Status = StatusDegraded
+				log.Printf("readiness check %s degraded: %v", check.name, err)
+			case err != nil:
+				result.Status = StatusFailing
+				log.Printf("readiness check %s failed: %v", check.name, err)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[check.name] = result
+			if result.Status == StatusFailing {
+				report.Status = StatusFailing
+			}
+		}()
+	}
+	wg.Wait()
+
+	if c.draining.Load() {
+		report.Status = StatusDraining
+	}
+	return report
+}
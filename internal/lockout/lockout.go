@@ -0,0 +1,90 @@
+// Package lockout slows down and eventually blocks repeated failed attempts,
+// such as password guesses, for a key like an email address or client IP.
+package lockout
+
+import (
+	"context"
+	"time"
+)
+
+// Store keeps failure counts and lock times. Implementations must be safe for
+// concurrent use, and a shared store lets every replica see the same counts.
+type Store interface {
+	// RecordFailure counts a failure for key and returns the new total. The count
+	// starts over when the previous failure is older than window.
+	RecordFailure(ctx context.Context, key string, window time.Duration) (int, error)
+	// Lock blocks key for the given duration from now. An existing later lock is kept.
+	// Shared stores measure it on their own clock, so replicas with skewed clocks agree.
+	Lock(ctx context.Context, key string, d time.Duration) error
+	// RetryAfter returns how long key stays locked, or zero if it isn't locked.
+	RetryAfter(ctx context.Context, key string) (time.Duration, error)
+	// Reset forgets every failure for key.
+	Reset(ctx context.Context, key string) error
+}
+
+// Policy decides how long a key is blocked after each failure.
+//
+// The first FreeAttempts failures cost nothing. After that every failure blocks
+// the key for BaseDelay, doubling each time up to MaxDelay. Once LockoutAfter
+// failures pile up the key is locked for LockoutDuration. Failures are
+// forgotten when none happen for Window; a zero Window never forgets them.
+type Policy struct {
+	FreeAttempts    int
+	BaseDelay       time.Duration
+	MaxDelay        time.Duration
+	LockoutAfter    int
+	LockoutDuration time.Duration
+	Window          time.Duration
+}
+
+// Delay returns how long the key is blocked after the given number of failures.
+func (p Policy) Delay(failures int) time.Duration {
+	if p.LockoutAfter > 0 && failures >= p.LockoutAfter {
+		return p.LockoutDuration
+	}
+	if failures <= p.FreeAttempts || p.BaseDelay <= 0 {
+		return 0
+	}
+	delay := p.BaseDelay
+	for i := p.FreeAttempts + 1; i < failures && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	return delay
+}
+
+// Guard applies one Policy to keys kept in a Store.
+type Guard struct {
+	Store  Store
+	Policy Policy
+	// Prefix namespaces the keys so several guards can share one store.
+	Prefix string
+}
+
+// Check returns how long the caller must wait before trying key again, or zero if it may try now.
+func (g *Guard) Check(ctx context.Context, key string) (time.Duration, error) {
+	return g.Store.RetryAfter(ctx, g.Prefix+key)
+}
+
+// Fail records a failed attempt for key and returns how long it is now blocked.
+func (g *Guard) Fail(ctx context.Context, key string) (time.Duration, error) {
+	failures, err := g.Store.RecordFailure(ctx, g.Prefix+key, g.Policy.Window)
+	if err != nil {
+		return 0, err
+	}
+	delay := g.Policy.Delay(failures)
+	if delay <= 0 {
+		return 0, nil
+	}
+	if err := g.Store.Lock(ctx, g.Prefix+key, delay); err != nil {
+		return 0, err
+	}
+	return delay, nil
+}
+
+// Succeed clears the failures recorded for key.
+func (g *Guard) Succeed(ctx context.Context, key string) error {
+	return g.Store.Reset(ctx, g.Prefix+key)
+}
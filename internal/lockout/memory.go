@@ -0,0 +1,106 @@
+package lockout
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepEvery is how many recorded failures pass between removals of stale entries.
+const sweepEvery = 1024
+
+type memoryEntry struct {
+	failures      int
+	lastFailureAt time.Time
+	lockedUntil   time.Time
+	window        time.Duration
+}
+
+// MemoryStore keeps attempts in process memory. Counts are lost on restart and
+// aren't shared between replicas, so it suits development and single instances.
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]*memoryEntry
+	writes  int
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: map[string]*memoryEntry{}}
+}
+
+func (m *MemoryStore) RecordFailure(ctx context.Context, key string, window time.Duration) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	entry, ok := m.entries[key]
+	if !ok {
+		entry = &memoryEntry{}
+		m.entries[key] = entry
+	}
+	if window > 0 && now.Sub(entry.lastFailureAt) > window {
+		entry.failures = 0
+	}
+	entry.failures++
+	entry.lastFailureAt = now
+	entry.window = window
+
+	m.writes++
+	if m.writes%sweepEvery == 0 {
+		m.sweep(now)
+	}
+	return entry.failures, nil
+}
+
+func (m *MemoryStore) Lock(ctx context.Context, key string, d time.Duration) error {
+	until := time.Now().Add(d)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.entries[key]
+	if !ok {
+		entry = &memoryEntry{}
+		m.entries[key] = entry
+	}
+	if until.After(entry.lockedUntil) {
+		entry.lockedUntil = until
+	}
+	return nil
+}
+
+func (m *MemoryStore) RetryAfter(ctx context.Context, key string) (time.Duration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.entries[key]
+	if !ok {
+		return 0, nil
+	}
+	wait := time.Until(entry.lockedUntil)
+	if wait < 0 {
+		return 0, nil
+	}
+	return wait, nil
+}
+
+func (m *MemoryStore) Reset(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.entries, key)
+	return nil
+}
+
+// sweep drops entries that are neither locked nor inside their failure window.
+func (m *MemoryStore) sweep(now time.Time) {
+	for key, entry := range m.entries {
+		if entry.lockedUntil.After(now) {
+			continue
+		}
+		if entry.window > 0 && now.Sub(entry.lastFailureAt) <= entry.window {
+			continue
+		}
+		delete(m.entries, key)
+	}
+}
@@ -0,0 +1,67 @@
+package lockout
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/Abo-Omar-74/httpServer/internal/database"
+)
+
+// foreverWindow stands in for a zero window, which means failures are never forgotten.
+const foreverWindow = 100 * 365 * 24 * time.Hour
+
+// PostgresStore keeps attempts in the login_attempts table so all replicas share them.
+type PostgresStore struct {
+	db     *database.Queries
+	writes atomic.Int64
+}
+
+func NewPostgresStore(db *database.Queries) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+func (p *PostgresStore) RecordFailure(ctx context.Context, key string, window time.Duration) (int, error) {
+	if window <= 0 {
+		window = foreverWindow
+	}
+	failures, err := p.db.RecordLoginFailure(ctx, database.RecordLoginFailureParams{
+		Key:           key,
+		WindowSeconds: window.Seconds(),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if p.writes.Add(1)%sweepEvery == 0 {
+		// Stale rows only cost space, so a failed cleanup doesn't fail the request.
+		p.db.DeleteStaleLoginAttempts(ctx, window.Seconds())
+	}
+	return int(failures), nil
+}
+
+// Lock and RetryAfter leave the arithmetic to the database. locked_until is a TIMESTAMP
+// compared with NOW(), so a deadline from the Go clock would be off by the host's UTC offset.
+func (p *PostgresStore) Lock(ctx context.Context, key string, d time.Duration) error {
+	return p.db.LockLoginAttempts(ctx, database.LockLoginAttemptsParams{
+		Key:         key,
+		LockSeconds: d.Seconds(),
+	})
+}
+
+func (p *PostgresStore) RetryAfter(ctx context.Context, key string) (time.Duration, error) {
+	seconds, err := p.db.GetLoginRetryAfter(ctx, key)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+func (p *PostgresStore) Reset(ctx context.Context, key string) error {
+	return p.db.DeleteLoginAttempts(ctx, key)
+}
@@ -0,0 +1,62 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Message is a plain text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers emails. Implementations must be safe for concurrent use.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// New returns the mailer named by kind: "log" (the default) or "file", which writes into dir.
+func New(kind string, dir string) (Mailer, error) {
+	switch strings.ToLower(kind) {
+	case "", "log":
+		return LogMailer{}, nil
+	case "file":
+		if dir == "" {
+			return nil, fmt.Errorf("file mailer needs a directory")
+		}
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, err
+		}
+		return FileMailer{Dir: dir}, nil
+	}
+	return nil, fmt.Errorf("unsupported mailer %q", kind)
+}
+
+// LogMailer writes emails to the standard logger instead of sending them. It is meant for local development.
+type LogMailer struct{}
+
+func (LogMailer) Send(ctx context.Context, msg Message) error {
+	log.Printf("Email to %s\nSubject: %s\n\n%s", msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+// FileMailer stores each email as an .eml file in Dir so it can be opened with a mail client.
+type FileMailer struct {
+	Dir string
+}
+
+func (f FileMailer) Send(ctx context.Context, msg Message) error {
+	name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405"), uuid.NewString())
+	content := fmt.Sprintf("To: %s\r\nSubject: %s\r\nDate: %s\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n%s\r\n",
+		msg.To, msg.Subject, time.Now().UTC().Format(time.RFC1123Z), msg.Body)
+	return os.WriteFile(filepath.Join(f.Dir, name), []byte(content), 0o600)
+}
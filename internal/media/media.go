@@ -0,0 +1,308 @@
+// Package media checks uploaded images and removes the metadata, such as EXIF
+// location data, that they carry.
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"net/http"
+)
+
+var (
+	ErrUnsupportedType = errors.New("unsupported media type")
+	ErrInvalidImage    = errors.New("invalid image")
+)
+
+// MaxPixels caps the width times height of an image, so a tiny file can't claim a huge canvas.
+const MaxPixels = 40_000_000
+
+// extensions maps the accepted content types to the file extension they are stored with.
+var extensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+// Image is an accepted upload with its metadata removed.
+type Image struct {
+	Data        []byte
+	ContentType string
+	Ext         string
+}
+
+// Process sniffs the content type from the bytes, ignoring whatever the client claimed,
+// rejects anything but JPEG, PNG, GIF and WebP images, and strips metadata blocks.
+// Pixels are left untouched, so JPEGs that relied on an EXIF orientation may show rotated.
+func Process(data []byte) (*Image, error) {
+	contentType := http.DetectContentType(data)
+	ext, ok := extensions[contentType]
+	if !ok {
+		return nil, ErrUnsupportedType
+	}
+
+	var stripped []byte
+	var err error
+	switch contentType {
+	case "image/jpeg":
+		stripped, err = stripJPEG(data)
+	case "image/png":
+		stripped, err = stripPNG(data)
+	case "image/gif":
+		stripped, err = stripGIF(data)
+	case "image/webp":
+		stripped, err = stripWebP(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	width, height, err := imageSize(contentType, stripped)
+	if err != nil || width <= 0 || height <= 0 || width*height > MaxPixels {
+		return nil, ErrInvalidImage
+	}
+	return &Image{Data: stripped, ContentType: contentType, Ext: ext}, nil
+}
+
+// imageSize reads the dimensions from the image header without decoding the pixels.
+func imageSize(contentType string, data []byte) (int, int, error) {
+	// The standard library can't decode WebP, so its size comes from the bitstream headers.
+	if contentType == "image/webp" {
+		return webpSize(data)
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return 0, 0, err
+	}
+	return config.Width, config.Height, nil
+}
+
+// stripJPEG drops the APP1 (EXIF and XMP), APP13 (IPTC) and comment segments.
+func stripJPEG(data []byte) ([]byte, error) {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil, ErrInvalidImage
+	}
+	out := make([]byte, 0, len(data))
+	out = append(out, 0xFF, 0xD8)
+	i := 2
+	for {
+		if i+2 > len(data) || data[i] != 0xFF {
+			return nil, ErrInvalidImage
+		}
+		marker := data[i+1]
+		switch {
+		case marker == 0xFF:
+			// Fill byte before a marker.
+			i++
+			continue
+		case marker == 0xD9:
+			return append(out, 0xFF, 0xD9), nil
+		case marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7):
+			out = append(out, data[i:i+2]...)
+			i += 2
+			continue
+		}
+		if i+4 > len(data) {
+			return nil, ErrInvalidImage
+		}
+		end := i + 2 + int(binary.BigEndian.Uint16(data[i+2:i+4]))
+		if end > len(data) || end < i+4 {
+			return nil, ErrInvalidImage
+		}
+		if marker == 0xDA {
+			// Start of scan: the compressed data runs to the end of the image.
+			return append(out, data[i:]...), nil
+		}
+		if marker != 0xE1 && marker != 0xED && marker != 0xFE {
+			out = append(out, data[i:end]...)
+		}
+		i = end
+	}
+}
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// pngMetadataChunks are the ancillary PNG chunks that carry metadata rather than pixels or color.
+var pngMetadataChunks = map[string]bool{
+	"eXIf": true,
+	"tEXt": true,
+	"zTXt": true,
+	"iTXt": true,
+	"tIME": true,
+}
+
+// stripPNG drops the EXIF, text and timestamp chunks.
+func stripPNG(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, pngSignature) {
+		return nil, ErrInvalidImage
+	}
+	out := make([]byte, 0, len(data))
+	out = append(out, pngSignature...)
+	i := len(pngSignature)
+	for i < len(data) {
+		if i+8 > len(data) {
+			return nil, ErrInvalidImage
+		}
+		length := int(binary.BigEndian.Uint32(data[i : i+4]))
+		chunkType := string(data[i+4 : i+8])
+		end := i + 12 + length
+		if length < 0 || end > len(data) || end < i {
+			return nil, ErrInvalidImage
+		}
+		if !pngMetadataChunks[chunkType] {
+			out = append(out, data[i:end]...)
+		}
+		i = end
+		if chunkType == "IEND" {
+			return out, nil
+		}
+	}
+	return nil, ErrInvalidImage
+}
+
+// stripGIF drops comment extensions and XMP application extensions.
+func stripGIF(data []byte) ([]byte, error) {
+	if len(data) < 13 {
+		return nil, ErrInvalidImage
+	}
+	i := 13
+	if data[10]&0x80 != 0 {
+		i += 3 << (data[10]&0x07 + 1)
+	}
+	if i > len(data) {
+		return nil, ErrInvalidImage
+	}
+	out := make([]byte, 0, len(data))
+	out = append(out, data[:i]...)
+	for i < len(data) {
+		start := i
+		switch data[i] {
+		case 0x3B:
+			return append(out, 0x3B), nil
+		case 0x21:
+			if i+2 > len(data) {
+				return nil, ErrInvalidImage
+			}
+			label := data[i+1]
+			end, ok := skipSubBlocks(data, i+2)
+			if !ok {
+				return nil, ErrInvalidImage
+			}
+			isXMP := label == 0xFF && bytes.HasPrefix(data[i+2:], []byte("\x0bXMP DataXMP"))
+			if label != 0xFE && !isXMP {
+				out = append(out, data[start:end]...)
+			}
+			i = end
+		case 0x2C:
+			if i+10 > len(data) {
+				return nil, ErrInvalidImage
+			}
+			flags := data[i+9]
+			i += 10
+			if flags&0x80 != 0 {
+				i += 3 << (flags&0x07 + 1)
+			}
+			// Skip the LZW minimum code size, then the image data sub-blocks.
+			end, ok := skipSubBlocks(data, i+1)
+			if !ok {
+				return nil, ErrInvalidImage
+			}
+			out = append(out, data[start:end]...)
+			i = end
+		default:
+			return nil, ErrInvalidImage
+		}
+	}
+	return nil, ErrInvalidImage
+}
+
+// skipSubBlocks returns the index just past the GIF sub-blocks starting at i, including the terminator.
+func skipSubBlocks(data []byte, i int) (int, bool) {
+	for i < len(data) {
+		size := int(data[i])
+		i++
+		if size == 0 {
+			return i, true
+		}
+		i += size
+	}
+	return 0, false
+}
+
+// stripWebP drops the EXIF and XMP chunks and clears their flags in the VP8X header.
+func stripWebP(data []byte) ([]byte, error) {
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil, ErrInvalidImage
+	}
+	out := make([]byte, 12, len(data))
+	copy(out, data[:12])
+	i := 12
+	for i < len(data) {
+		if i+8 > len(data) {
+			return nil, ErrInvalidImage
+		}
+		fourCC := string(data[i : i+4])
+		size := int(binary.LittleEndian.Uint32(data[i+4 : i+8]))
+		end := i + 8 + size + size%2
+		if size < 0 || end > len(data) || end < i {
+			return nil, ErrInvalidImage
+		}
+		switch fourCC {
+		case "EXIF", "XMP ":
+		case "VP8X":
+			chunk := append([]byte(nil), data[i:end]...)
+			if len(chunk) > 8 {
+				chunk[8] &^= 0x08 | 0x04
+			}
+			out = append(out, chunk...)
+		default:
+			out = append(out, data[i:end]...)
+		}
+		i = end
+	}
+	binary.LittleEndian.PutUint32(out[4:8], uint32(len(out)-8))
+	return out, nil
+}
+
+// webpSize returns the canvas size from the VP8X header, or for a simple file the
+// frame size from its VP8 (lossy) or VP8L (lossless) bitstream header.
+func webpSize(data []byte) (int, int, error) {
+	if len(data) < 20 {
+		return 0, 0, ErrInvalidImage
+	}
+	// stripWebP has already checked the chunk sizes, and the first chunk decides the layout.
+	fourCC := string(data[12:16])
+	size := int(binary.LittleEndian.Uint32(data[16:20]))
+	if 20+size > len(data) {
+		return 0, 0, ErrInvalidImage
+	}
+	payload := data[20 : 20+size]
+	switch fourCC {
+	case "VP8X":
+		if len(payload) < 10 {
+			return 0, 0, ErrInvalidImage
+		}
+		width := int(payload[4]) | int(payload[5])<<8 | int(payload[6])<<16
+		height := int(payload[7]) | int(payload[8])<<8 | int(payload[9])<<16
+		return width + 1, height + 1, nil
+	case "VP8 ":
+		if len(payload) < 10 || payload[3] != 0x9D || payload[4] != 0x01 || payload[5] != 0x2A {
+			return 0, 0, ErrInvalidImage
+		}
+		width := int(binary.LittleEndian.Uint16(payload[6:8]) & 0x3FFF)
+		height := int(binary.LittleEndian.Uint16(payload[8:10]) & 0x3FFF)
+		return width, height, nil
+	case "VP8L":
+		if len(payload) < 5 || payload[0] != 0x2F {
+			return 0, 0, ErrInvalidImage
+		}
+		bits := binary.LittleEndian.Uint32(payload[1:5])
+		return int(bits&0x3FFF) + 1, int(bits>>14&0x3FFF) + 1, nil
+	}
+	return 0, 0, ErrInvalidImage
+}
@@ -0,0 +1,377 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// secret marks the metadata planted in the fixtures. No processed image may contain it.
+const secret = "secret-location"
+
+func testImage() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 3))
+	for x := 0; x < 4; x++ {
+		for y := 0; y < 3; y++ {
+			img.Set(x, y, color.RGBA{uint8(x * 60), uint8(y * 80), 200, 255})
+		}
+	}
+	return img
+}
+
+func jpegSegment(marker byte, payload string) []byte {
+	segment := []byte{0xFF, marker, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	return append(segment, payload...)
+}
+
+// jpegWithMetadata returns a JPEG carrying EXIF, XMP, IPTC and comment segments.
+func jpegWithMetadata(t testing.TB) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, testImage(), nil); err != nil {
+		t.Fatal(err)
+	}
+	encoded := buf.Bytes()
+
+	var data []byte
+	data = append(data, encoded[:2]...)
+	data = append(data, jpegSegment(0xE1, "Exif\x00\x00GPS "+secret)...)
+	data = append(data, jpegSegment(0xE1, "http://ns.adobe.com/xap/1.0/\x00<x:xmpmeta>"+secret+"</x:xmpmeta>")...)
+	data = append(data, jpegSegment(0xED, "Photoshop 3.0\x00"+secret)...)
+	data = append(data, jpegSegment(0xFE, secret)...)
+	return append(data, encoded[2:]...)
+}
+
+func pngChunk(chunkType string, payload []byte) []byte {
+	chunk := make([]byte, 4, 12+len(payload))
+	binary.BigEndian.PutUint32(chunk, uint32(len(payload)))
+	chunk = append(chunk, chunkType...)
+	chunk = append(chunk, payload...)
+	return binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+}
+
+// pngWithMetadata returns a PNG carrying EXIF, text and timestamp chunks after its header.
+func pngWithMetadata(t testing.TB) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, testImage()); err != nil {
+		t.Fatal(err)
+	}
+	encoded := buf.Bytes()
+	// The signature and the IHDR chunk come first.
+	headerEnd := len(pngSignature) + 12 + 13
+
+	var data []byte
+	data = append(data, encoded[:headerEnd]...)
+	data = append(data, pngChunk("eXIf", []byte("MM\x00*GPS "+secret))...)
+	data = append(data, pngChunk("tEXt", []byte("Comment\x00"+secret))...)
+	data = append(data, pngChunk("zTXt", []byte("Comment\x00\x00"+secret))...)
+	data = append(data, pngChunk("iTXt", []byte("XML:com.adobe.xmp\x00\x00\x00\x00\x00"+secret))...)
+	data = append(data, pngChunk("tIME", []byte{0x07, 0xEA, 10, 18, 12, 0, 0})...)
+	return append(data, encoded[headerEnd:]...)
+}
+
+func gifSubBlocks(payload string) []byte {
+	var blocks []byte
+	for len(payload) > 0 {
+		n := min(len(payload), 255)
+		blocks = append(blocks, byte(n))
+		blocks = append(blocks, payload[:n]...)
+		payload = payload[n:]
+	}
+	return append(blocks, 0)
+}
+
+// netscapeLoop is the application extension that makes a GIF loop. It is not metadata and must survive.
+var netscapeLoop = []byte("\x21\xFF\x0BNETSCAPE2.0\x03\x01\x00\x00\x00")
+
+// gifWithMetadata returns a GIF carrying a comment and an XMP application extension.
+func gifWithMetadata(t testing.TB) []byte {
+	t.Helper()
+	img := image.NewPaletted(image.Rect(0, 0, 4, 3), palette.Plan9)
+	for x := 0; x < 4; x++ {
+		img.SetColorIndex(x, x%3, uint8(x*40))
+	}
+	var buf bytes.Buffer
+	if err := gif.Encode(&buf, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	encoded := buf.Bytes()
+	trailer := len(encoded) - 1
+
+	var data []byte
+	data = append(data, encoded[:trailer]...)
+	data = append(data, 0x21, 0xFE)
+	data = append(data, gifSubBlocks(secret)...)
+	data = append(data, "\x21\xFF\x0BXMP DataXMP"...)
+	data = append(data, gifSubBlocks("<x:xmpmeta>"+secret+"</x:xmpmeta>")...)
+	data = append(data, netscapeLoop...)
+	return append(data, encoded[trailer:]...)
+}
+
+func webpChunk(fourCC string, payload []byte) []byte {
+	chunk := append([]byte(fourCC), 0, 0, 0, 0)
+	binary.LittleEndian.PutUint32(chunk[4:], uint32(len(payload)))
+	chunk = append(chunk, payload...)
+	if len(payload)%2 == 1 {
+		chunk = append(chunk, 0)
+	}
+	return chunk
+}
+
+func webpFile(chunks ...[]byte) []byte {
+	data := []byte("RIFF\x00\x00\x00\x00WEBP")
+	for _, chunk := range chunks {
+		data = append(data, chunk...)
+	}
+	binary.LittleEndian.PutUint32(data[4:8], uint32(len(data)-8))
+	return data
+}
+
+// vp8xChunk describes an extended WebP canvas. Flags 0x08 and 0x04 announce EXIF and XMP chunks.
+func vp8xChunk(flags byte, width, height int) []byte {
+	payload := []byte{flags, 0, 0, 0}
+	payload = append(payload, byte(width-1), byte((width-1)>>8), byte((width-1)>>16))
+	payload = append(payload, byte(height-1), byte((height-1)>>8), byte((height-1)>>16))
+	return webpChunk("VP8X", payload)
+}
+
+// vp8lChunk is a lossless bitstream header followed by filler in place of the image data.
+func vp8lChunk(width, height int) []byte {
+	payload := []byte{0x2F}
+	payload = binary.LittleEndian.AppendUint32(payload, uint32(width-1)|uint32(height-1)<<14)
+	return webpChunk("VP8L", append(payload, 0, 0, 0))
+}
+
+// vp8Chunk is a lossy key frame header followed by filler in place of the image data.
+func vp8Chunk(width, height int) []byte {
+	payload := []byte{0x10, 0x02, 0x00, 0x9D, 0x01, 0x2A}
+	payload = binary.LittleEndian.AppendUint16(payload, uint16(width))
+	payload = binary.LittleEndian.AppendUint16(payload, uint16(height))
+	return webpChunk("VP8 ", append(payload, 0, 0))
+}
+
+// webpWithMetadata returns an extended WebP carrying EXIF and XMP chunks.
+func webpWithMetadata() []byte {
+	return webpFile(
+		vp8xChunk(0x08|0x04, 4, 3),
+		vp8lChunk(4, 3),
+		webpChunk("EXIF", []byte("MM\x00*GPS "+secret)),
+		webpChunk("XMP ", []byte("<x:xmpmeta>"+secret+"</x:xmpmeta>")),
+	)
+}
+
+func TestProcessStripsMetadata(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        []byte
+		contentType string
+		ext         string
+		// decode checks that the stripped file is still a valid image of the original size.
+		decode func(data []byte) (image.Image, error)
+		// gone are markers of the metadata blocks that must not survive.
+		gone []string
+		// kept are markers of blocks that must survive.
+		kept []string
+	}{
+		{
+			name:        "jpeg",
+			data:        jpegWithMetadata(t),
+			contentType: "image/jpeg",
+			ext:         ".jpg",
+			decode:      func(data []byte) (image.Image, error) { return jpeg.Decode(bytes.NewReader(data)) },
+			gone:        []string{"Exif\x00\x00", "http://ns.adobe.com/xap/1.0/", "Photoshop 3.0"},
+		},
+		{
+			name:        "png",
+			data:        pngWithMetadata(t),
+			contentType: "image/png",
+			ext:         ".png",
+			decode:      func(data []byte) (image.Image, error) { return png.Decode(bytes.NewReader(data)) },
+			gone:        []string{"eXIf", "tEXt", "zTXt", "iTXt", "tIME"},
+			kept:        []string{"IHDR", "IDAT", "IEND"},
+		},
+		{
+			name:        "gif",
+			data:        gifWithMetadata(t),
+			contentType: "image/gif",
+			ext:         ".gif",
+			decode:      func(data []byte) (image.Image, error) { return gif.Decode(bytes.NewReader(data)) },
+			gone:        []string{"XMP DataXMP"},
+			kept:        []string{string(netscapeLoop)},
+		},
+		{
+			name:        "webp",
+			data:        webpWithMetadata(),
+			contentType: "image/webp",
+			ext:         ".webp",
+			gone:        []string{"EXIF", "XMP "},
+			kept:        []string{"VP8X", "VP8L"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !bytes.Contains(tt.data, []byte(secret)) {
+				t.Fatal("fixture has no metadata to strip")
+			}
+
+			img, err := Process(tt.data)
+			if err != nil {
+				t.Fatalf("Process returned error: %v", err)
+			}
+			if img.ContentType != tt.contentType || img.Ext != tt.ext {
+				t.Errorf("got %s (%s), want %s (%s)", img.ContentType, img.Ext, tt.contentType, tt.ext)
+			}
+			if bytes.Contains(img.Data, []byte(secret)) {
+				t.Error("metadata survived processing")
+			}
+			for _, marker := range tt.gone {
+				if bytes.Contains(img.Data, []byte(marker)) {
+					t.Errorf("output still contains %q", marker)
+				}
+			}
+			for _, marker := range tt.kept {
+				if !bytes.Contains(img.Data, []byte(marker)) {
+					t.Errorf("output lost %q", marker)
+				}
+			}
+			if tt.decode != nil {
+				decoded, err := tt.decode(img.Data)
+				if err != nil {
+					t.Fatalf("stripped image doesn't decode: %v", err)
+				}
+				if got := decoded.Bounds().Size(); got != image.Pt(4, 3) {
+					t.Errorf("decoded size = %v, want 4x3", got)
+				}
+			}
+		})
+	}
+}
+
+func TestProcessWebPHeaders(t *testing.T) {
+	img, err := Process(webpWithMetadata())
+	if err != nil {
+		t.Fatalf("Process returned error: %v", err)
+	}
+	data := img.Data
+	if size := binary.LittleEndian.Uint32(data[4:8]); int(size) != len(data)-8 {
+		t.Errorf("RIFF size = %d, want %d", size, len(data)-8)
+	}
+	if flags := data[20]; flags&(0x08|0x04) != 0 {
+		t.Errorf("VP8X flags = %#x, still announce EXIF or XMP", flags)
+	}
+}
+
+func TestProcessRejectsOversizedImages(t *testing.T) {
+	var pngHeader []byte
+	pngHeader = append(pngHeader, pngSignature...)
+	ihdr := binary.BigEndian.AppendUint32(nil, 10_000)
+	ihdr = binary.BigEndian.AppendUint32(ihdr, 10_000)
+	ihdr = append(ihdr, 8, 6, 0, 0, 0)
+	pngHeader = append(pngHeader, pngChunk("IHDR", ihdr)...)
+	pngHeader = append(pngHeader, pngChunk("IEND", nil)...)
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"png", pngHeader},
+		{"webp extended canvas", webpFile(vp8xChunk(0, 10_000, 10_000), vp8lChunk(4, 3))},
+		{"webp lossless", webpFile(vp8lChunk(16_384, 16_384))},
+		{"webp lossy", webpFile(vp8Chunk(8_000, 6_000))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Process(tt.data); !errors.Is(err, ErrInvalidImage) {
+				t.Errorf("Process returned %v, want ErrInvalidImage", err)
+			}
+		})
+	}
+}
+
+func TestProcessAcceptsSimpleWebP(t *testing.T) {
+	for name, data := range map[string][]byte{
+		"lossless": webpFile(vp8lChunk(640, 480)),
+		"lossy":    webpFile(vp8Chunk(640, 480)),
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Process(data); err != nil {
+				t.Errorf("Process returned error: %v", err)
+			}
+		})
+	}
+}
+
+func TestProcessRejectsUnsupportedTypes(t *testing.T) {
+	for _, data := range [][]byte{
+		[]byte("just some text"),
+		[]byte("<svg xmlns=\"http://www.w3.org/2000/svg\"></svg>"),
+		[]byte("%PDF-1.7\n"),
+	} {
+		if _, err := Process(data); !errors.Is(err, ErrUnsupportedType) {
+			t.Errorf("Process(%q) returned %v, want ErrUnsupportedType", data, err)
+		}
+	}
+}
+
+func TestProcessRejectsTruncatedImages(t *testing.T) {
+	for name, data := range map[string][]byte{
+		"jpeg": jpegWithMetadata(t),
+		"png":  pngWithMetadata(t),
+		"gif":  gifWithMetadata(t),
+		"webp": webpWithMetadata(),
+	} {
+		t.Run(name, func(t *testing.T) {
+			// Every prefix must fail cleanly rather than panic or pass metadata through.
+			for n := range len(data) {
+				img, err := Process(data[:n])
+				if err == nil && bytes.Contains(img.Data, []byte(secret)) {
+					t.Fatalf("prefix of %d bytes kept metadata", n)
+				}
+			}
+		})
+	}
+}
+
+func FuzzProcess(f *testing.F) {
+	f.Add(jpegWithMetadata(f))
+	f.Add(pngWithMetadata(f))
+	f.Add(gifWithMetadata(f))
+	f.Add(webpWithMetadata())
+	f.Add(webpFile(vp8Chunk(4, 3)))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		img, err := Process(data)
+		if err != nil {
+			if !errors.Is(err, ErrInvalidImage) && !errors.Is(err, ErrUnsupportedType) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			return
+		}
+		if extensions[img.ContentType] != img.Ext {
+			t.Fatalf("content type %s stored as %s", img.ContentType, img.Ext)
+		}
+		if len(img.Data) > len(data) {
+			t.Fatalf("output grew from %d to %d bytes", len(data), len(img.Data))
+		}
+
+		// Stripping has to be complete in one pass, so a second pass changes nothing.
+		again, err := Process(img.Data)
+		if err != nil {
+			t.Fatalf("processed image rejected on second pass: %v", err)
+		}
+		if !bytes.Equal(again.Data, img.Data) {
+			t.Fatal("second pass stripped more data")
+		}
+	})
+}
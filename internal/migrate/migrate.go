@@ -0,0 +1,428 @@
+// Package migrate applies the goose migration files in sql/schema from inside the server binary.
+//
+// Applied versions are kept in goose's own goose_db_version table, so a database
+// migrated with the goose CLI and one migrated here look the same.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const versionTable = "goose_db_version"
+
+// lockID is the Postgres advisory lock key held while migrations run, so that
+// replicas starting together do not apply the same migration twice.
+const lockID int64 = 0x636869727079 // "chirpy"
+
+// ErrNoMigration is returned by Down and Redo when nothing has been applied yet.
+var ErrNoMigration = errors.New("no applied migration to roll back")
+
+// Migration is one schema file, split into the statements of its Up and Down sections.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      []string
+	Down    []string
+	// NoTransaction is set by "-- +goose NO TRANSACTION" for statements such as
+	// CREATE INDEX CONCURRENTLY that cannot run inside a transaction.
+	NoTransaction bool
+}
+
+// Status reports whether a migration has been applied to the database.
+type Status struct {
+	Migration Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// Migrator applies Migrations to DB.
+type Migrator struct {
+	DB         *sql.DB
+	Migrations []Migration
+}
+
+// New reads every .sql file in fsys and returns a Migrator for db.
+func New(db *sql.DB, fsys fs.FS) (*Migrator, error) {
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{DB: db, Migrations: migrations}, nil
+}
+
+// Load parses the migration files in fsys, sorted by version. File names start
+// with the version number, as in 001_users.sql.
+func Load(fsys fs.FS) ([]Migration, error) {
+	names, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[int64]string{}
+	migrations := make([]Migration, 0, len(names))
+	for _, name := range names {
+		prefix, _, _ := strings.Cut(name, "_")
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %s: name must start with a positive version number", name)
+		}
+		if other, ok := seen[version]; ok {
+			return nil, fmt.Errorf("migrations %s and %s share version %d", other, name, version)
+		}
+		seen[version] = name
+
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		migration, err := parse(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: %w", name, err)
+		}
+		migration.Version = version
+		migration.Name = strings.TrimSuffix(path.Base(name), ".sql")
+		migrations = append(migrations, migration)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// parse splits a goose file on its "-- +goose Up" and "-- +goose Down" annotations.
+// Like goose, a statement ends at a line ending in a semicolon, unless it is wrapped
+// in StatementBegin and StatementEnd, as function bodies containing semicolons must be.
+func parse(data string) (Migration, error) {
+	var migration Migration
+	var section *[]string
+	var statement strings.Builder
+	inBlock := false
+
+	flush := func() {
+		if text := strings.TrimSpace(statement.String()); text != "" && section != nil {
+			*section = append(*section, text)
+		}
+		statement.Reset()
+	}
+
+	for _, line := range strings.SplitAfter(data, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if rest, ok := strings.CutPrefix(trimmed, "--"); ok {
+			if directive, ok := strings.CutPrefix(strings.TrimSpace(rest), "+goose"); ok {
+				switch strings.ToUpper(strings.TrimSpace(directive)) {
+				case "UP":
+					flush()
+					section = &migration.Up
+				case "DOWN":
+					flush()
+					section = &migration.Down
+				case "NO TRANSACTION":
+					migration.NoTransaction = true
+				case "STATEMENTBEGIN":
+					flush()
+					inBlock = true
+				case "STATEMENTEND":
+					flush()
+					inBlock = false
+				default:
+					return Migration{}, fmt.Errorf("unknown annotation %q", trimmed)
+				}
+				continue
+			}
+			if statement.Len() == 0 {
+				// Comments between statements would otherwise become statements of their own.
+				continue
+			}
+		}
+		if section == nil {
+			continue
+		}
+		statement.WriteString(line)
+		if !inBlock && endsWithSemicolon(line) {
+			flush()
+		}
+	}
+	if inBlock {
+		return Migration{}, errors.New("StatementBegin without StatementEnd")
+	}
+	flush()
+
+	if len(migration.Up) == 0 {
+		return Migration{}, errors.New("missing -- +goose Up section")
+	}
+	return migration, nil
+}
+
+// endsWithSemicolon reports whether the line ends a statement. As in goose, a trailing
+// "--" comment is ignored, so "DROP TABLE users; -- old" ends one.
+func endsWithSemicolon(line string) bool {
+	last := ""
+	for _, word := range strings.Fields(line) {
+		if strings.HasPrefix(word, "--") {
+			break
+		}
+		last = word
+	}
+	return strings.HasSuffix(last, ";")
+}
+
+// Latest is the highest version known to the binary.
+func (m *Migrator) Latest() int64 {
+	if len(m.Migrations) == 0 {
+		return 0
+	}
+	return m.Migrations[len(m.Migrations)-1].Version
+}
+
+// Status lists every known migration and whether it has been applied.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	applied, err := appliedVersions(ctx, m.DB)
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]Status, 0, len(m.Migrations))
+	for _, migration := range m.Migrations {
+		appliedAt, ok := applied[migration.Version]
+		statuses = append(statuses, Status{Migration: migration, Applied: ok, AppliedAt: appliedAt})
+	}
+	return statuses, nil
+}
+
+// Version returns the highest applied version, or 0 on an empty database.
+func (m *Migrator) Version(ctx context.Context) (int64, error) {
+	applied, err := appliedVersions(ctx, m.DB)
+	if err != nil {
+		return 0, err
+	}
+	var version int64
+	for v := range applied {
+		version = max(version, v)
+	}
+	return version, nil
+}
+
+// Pending lists the migrations that have not been applied yet.
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) {
+	applied, err := appliedVersions(ctx, m.DB)
+	if err != nil {
+		return nil, err
+	}
+	return pending(m.Migrations, applied), nil
+}
+
+// Up applies every pending migration in version order and returns the ones it ran.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var ran []Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, migration := range pending(m.Migrations, applied) {
+			if err := run(ctx, conn, migration, true); err != nil {
+				return err
+			}
+			ran = append(ran, migration)
+		}
+		return nil
+	})
+	return ran, err
+}
+
+// Down rolls back the most recently applied migration.
+func (m *Migrator) Down(ctx context.Context) (Migration, error) {
+	var rolledBack Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		migration, err := m.lastApplied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		rolledBack = migration
+		return run(ctx, conn, migration, false)
+	})
+	return rolledBack, err
+}
+
+// Redo rolls back the most recently applied migration and applies it again.
+func (m *Migrator) Redo(ctx context.Context) (Migration, error) {
+	var redone Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		migration, err := m.lastApplied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		redone = migration
+		if err := run(ctx, conn, migration, false); err != nil {
+			return err
+		}
+		return run(ctx, conn, migration, true)
+	})
+	return redone, err
+}
+
+func (m *Migrator) lastApplied(ctx context.Context, conn *sql.Conn) (Migration, error) {
+	applied, err := appliedVersions(ctx, conn)
+	if err != nil {
+		return Migration{}, err
+	}
+	for i := len(m.Migrations) - 1; i >= 0; i-- {
+		if _, ok := applied[m.Migrations[i].Version]; ok {
+			return m.Migrations[i], nil
+		}
+	}
+	return Migration{}, ErrNoMigration
+}
+
+// withLock runs fn on a single connection that holds the migration advisory lock.
+// The version table is created first if it does not exist yet.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.DB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", lockID); err != nil {
+		return fmt.Errorf("take migration lock: %w", err)
+	}
+	// Unlock with a fresh context so a cancelled ctx still releases the lock.
+	defer conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", lockID)
+
+	if err := createVersionTable(ctx, conn); err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+func createVersionTable(ctx context.Context, conn *sql.Conn) error {
+	var exists bool
+	if err := conn.QueryRowContext(ctx, "SELECT to_regclass($1) IS NOT NULL", versionTable).Scan(&exists); err != nil {
+		return err
+	}
+	if exists {
+		return nil
+	}
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, `CREATE TABLE `+versionTable+` (
+  id serial PRIMARY KEY,
+  version_id bigint NOT NULL,
+  is_applied boolean NOT NULL,
+  tstamp timestamp DEFAULT now()
+)`); err != nil {
+		return fmt.Errorf("create %s: %w", versionTable, err)
+	}
+	// goose records version 0 when it creates the table.
+	if _, err := tx.ExecContext(ctx, "INSERT INTO "+versionTable+" (version_id, is_applied) VALUES (0, true)"); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// run applies one direction of a migration and records it in the version table.
+func run(ctx context.Context, conn *sql.Conn, migration Migration, up bool) error {
+	direction, statements := "down", migration.Down
+	record := "DELETE FROM " + versionTable + " WHERE version_id = $1"
+	if up {
+		direction, statements = "up", migration.Up
+		record = "INSERT INTO " + versionTable + " (version_id, is_applied) VALUES ($1, true)"
+	}
+	wrap := func(err error) error {
+		return fmt.Errorf("migration %s %s: %w", migration.Name, direction, err)
+	}
+
+	// Statements go one at a time: Postgres runs a multi-statement string as one
+	// implicit transaction, which would defeat NO TRANSACTION.
+	if migration.NoTransaction {
+		for _, statement := range statements {
+			if _, err := conn.ExecContext(ctx, statement); err != nil {
+				return wrap(err)
+			}
+		}
+		if _, err := conn.ExecContext(ctx, record, migration.Version); err != nil {
+			return wrap(err)
+		}
+		return nil
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return wrap(err)
+	}
+	defer tx.Rollback()
+	for _, statement := range statements {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			return wrap(err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, record, migration.Version); err != nil {
+		return wrap(err)
+	}
+	if err := tx.Commit(); err != nil {
+		return wrap(err)
+	}
+	return nil
+}
+
+type querier interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// appliedVersions maps each applied version to when it was applied. Older goose
+// versions recorded a rollback as an is_applied = false row, so only the newest
+// row of each version counts.
+func appliedVersions(ctx context.Context, q querier) (map[int64]time.Time, error) {
+	applied := map[int64]time.Time{}
+	var exists bool
+	if err := q.QueryRowContext(ctx, "SELECT to_regclass($1) IS NOT NULL", versionTable).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return applied, nil
+	}
+
+	rows, err := q.QueryContext(ctx, `SELECT DISTINCT ON (version_id) version_id, is_applied, tstamp
+FROM `+versionTable+`
+WHERE version_id > 0
+ORDER BY version_id, id DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var version int64
+		var isApplied bool
+		var appliedAt sql.NullTime
+		if err := rows.Scan(&version, &isApplied, &appliedAt); err != nil {
+			return nil, err
+		}
+		if isApplied {
+			applied[version] = appliedAt.Time
+		}
+	}
+	return applied, rows.Err()
+}
+
+func pending(migrations []Migration, applied map[int64]time.Time) []Migration {
+	var out []Migration
+	for _, migration := range migrations {
+		if _, ok := applied[migration.Version]; !ok {
+			out = append(out, migration)
+		}
+	}
+	return out
+}
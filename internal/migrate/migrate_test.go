@@ -0,0 +1,222 @@
+package migrate
+
+import (
+	"io/fs"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/Abo-Omar-74/httpServer/sql/schema"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name          string
+		file          string
+		up            []string
+		down          []string
+		noTransaction bool
+	}{
+		{
+			name: "one statement per semicolon",
+			file: `-- +goose Up
+CREATE TABLE a(id int);
+CREATE TABLE b(
+  id int
+);
+
+-- +goose Down
+DROP TABLE b;
+DROP TABLE a;
+`,
+			up:   []string{"CREATE TABLE a(id int);", "CREATE TABLE b(\n  id int\n);"},
+			down: []string{"DROP TABLE b;", "DROP TABLE a;"},
+		},
+		{
+			name: "comments",
+			file: `-- A header comment before any section.
+-- +goose Up
+-- Comments between statements are dropped.
+CREATE TABLE a(
+  -- but kept inside one.
+  id int
+);
+CREATE TABLE b(id int); -- a trailing comment ends the statement
+-- +goose Down
+-- a comment ending in a semicolon doesn't end anything;
+DROP TABLE a;
+`,
+			up: []string{
+				"CREATE TABLE a(\n  -- but kept inside one.\n  id int\n);",
+				"CREATE TABLE b(id int); -- a trailing comment ends the statement",
+			},
+			down: []string{"DROP TABLE a;"},
+		},
+		{
+			name: "statement blocks",
+			file: `-- +goose Up
+-- +goose StatementBegin
+CREATE FUNCTION touch() RETURNS trigger AS $$
+BEGIN
+  NEW.updated_at = NOW();
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+CREATE TABLE a(id int);
+
+-- +goose Down
+DROP FUNCTION touch;
+`,
+			up: []string{
+				"CREATE FUNCTION touch() RETURNS trigger AS $$\nBEGIN\n  NEW.updated_at = NOW();\n  RETURN NEW;\nEND;\n$$ LANGUAGE plpgsql;",
+				"CREATE TABLE a(id int);",
+			},
+			down: []string{"DROP FUNCTION touch;"},
+		},
+		{
+			name: "no transaction",
+			file: `-- +goose NO TRANSACTION
+-- +goose Up
+CREATE INDEX CONCURRENTLY a_id_idx ON a(id);
+CREATE INDEX CONCURRENTLY a_name_idx ON a(name);
+
+-- +goose Down
+DROP INDEX CONCURRENTLY a_name_idx;
+DROP INDEX CONCURRENTLY a_id_idx;
+`,
+			up:            []string{"CREATE INDEX CONCURRENTLY a_id_idx ON a(id);", "CREATE INDEX CONCURRENTLY a_name_idx ON a(name);"},
+			down:          []string{"DROP INDEX CONCURRENTLY a_name_idx;", "DROP INDEX CONCURRENTLY a_id_idx;"},
+			noTransaction: true,
+		},
+		{
+			name: "last statement without a semicolon",
+			file: "-- +goose Up\nSELECT 1\n-- +goose Down\nSELECT 2",
+			up:   []string{"SELECT 1"},
+			down: []string{"SELECT 2"},
+		},
+		{
+			name: "no down section",
+			file: "-- +goose Up\nSELECT 1;\n",
+			up:   []string{"SELECT 1;"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			migration, err := parse(tt.file)
+			if err != nil {
+				t.Fatalf("parse returned error: %v", err)
+			}
+			if !reflect.DeepEqual(migration.Up, tt.up) {
+				t.Errorf("Up = %q, want %q", migration.Up, tt.up)
+			}
+			if !reflect.DeepEqual(migration.Down, tt.down) {
+				t.Errorf("Down = %q, want %q", migration.Down, tt.down)
+			}
+			if migration.NoTransaction != tt.noTransaction {
+				t.Errorf("NoTransaction = %v, want %v", migration.NoTransaction, tt.noTransaction)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := map[string]string{
+		"missing up":            "-- +goose Down\nDROP TABLE a;\n",
+		"empty up":              "-- +goose Up\n-- +goose Down\nDROP TABLE a;\n",
+		"unclosed block":        "-- +goose Up\n-- +goose StatementBegin\nSELECT 1;\n",
+		"unknown annotation":    "-- +goose Up\n-- +goose Sideways\nSELECT 1;\n",
+		"only comments in file": "-- nothing here\n",
+	}
+	for name, file := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := parse(file); err == nil {
+				t.Error("parse returned no error")
+			}
+		})
+	}
+}
+
+// TestLoadSchema parses the migrations the server ships with.
+func TestLoadSchema(t *testing.T) {
+	migrations, err := Load(schema.FS)
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	names, err := fs.Glob(schema.FS, "*.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) != len(names) {
+		t.Fatalf("loaded %d migrations from %d files", len(migrations), len(names))
+	}
+
+	for i, migration := range migrations {
+		if i > 0 && migration.Version <= migrations[i-1].Version {
+			t.Errorf("%s is out of order after %s", migration.Name, migrations[i-1].Name)
+		}
+		if len(migration.Down) == 0 {
+			t.Errorf("%s has no Down section", migration.Name)
+		}
+		for _, statement := range append(append([]string{}, migration.Up...), migration.Down...) {
+			// None of the files use StatementBegin, so every statement is a single
+			// line-terminated command with no annotations or extra commands glued on.
+			if strings.Contains(statement, "+goose") {
+				t.Errorf("%s: annotation inside statement %q", migration.Name, statement)
+			}
+			if !strings.HasSuffix(statement, ";") || strings.Count(statement, ";") != 1 {
+				t.Errorf("%s: statement %q is not exactly one command", migration.Name, statement)
+			}
+		}
+	}
+
+	byName := map[string]Migration{}
+	for _, migration := range migrations {
+		byName[migration.Name] = migration
+	}
+	search, ok := byName["022_posts_search"]
+	if !ok {
+		t.Fatal("022_posts_search is missing")
+	}
+	if search.Version != 22 || len(search.Up) != 2 || len(search.Down) != 2 || search.NoTransaction {
+		t.Errorf("022_posts_search = version %d, %d up and %d down statements, no transaction %v; want 22, 2, 2, false",
+			search.Version, len(search.Up), len(search.Down), search.NoTransaction)
+	}
+	if want := "CREATE INDEX posts_search_vector_idx ON posts USING GIN(search_vector);"; search.Up[1] != want {
+		t.Errorf("022_posts_search second statement = %q, want %q", search.Up[1], want)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	file := &fstest.MapFile{Data: []byte("-- +goose Up\nSELECT 1;\n")}
+	tests := map[string]fstest.MapFS{
+		"shared version": {"001_a.sql": file, "01_b.sql": file},
+		"no version":     {"users.sql": file},
+		"zero version":   {"000_users.sql": file},
+		"bad file":       {"001_a.sql": &fstest.MapFile{Data: []byte("SELECT 1;\n")}},
+	}
+	for name, fsys := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Load(fsys); err == nil {
+				t.Error("Load returned no error")
+			}
+		})
+	}
+}
+
+func TestLoadSortsByVersion(t *testing.T) {
+	file := &fstest.MapFile{Data: []byte("-- +goose Up\nSELECT 1;\n")}
+	migrations, err := Load(fstest.MapFS{"10_c.sql": file, "2_b.sql": file, "001_a.sql": file})
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	var got []string
+	for _, migration := range migrations {
+		got = append(got, migration.Name)
+	}
+	if want := []string{"001_a", "2_b", "10_c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("order = %v, want %v", got, want)
+	}
+}
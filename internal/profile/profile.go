@@ -0,0 +1,95 @@
+// Package profile cleans and validates the public profile fields a user can set.
+package profile
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/Abo-Omar-74/httpServer/internal/content"
+)
+
+const (
+	MaxDisplayNameLength = 50
+	MaxBioLength         = 160
+	MaxAvatarURLLength   = 2048
+)
+
+// handlePattern allows 3 to 30 lowercase letters, digits and underscores.
+var handlePattern = regexp.MustCompile(`^[a-z0-9_]{3,30}$`)
+
+var (
+	displayNamePipeline = content.NewPipeline(content.Normalize(), content.MaxLength(MaxDisplayNameLength))
+	bioPipeline         = content.NewPipeline(content.Normalize(), content.MaxLength(MaxBioLength))
+)
+
+// Fields are the editable parts of a profile. An empty Handle means the user has none.
+type Fields struct {
+	Handle      string
+	DisplayName string
+	Bio         string
+	AvatarURL   string
+}
+
+// NormalizeHandle lowercases a handle and drops a leading "@", so lookups ignore case.
+func NormalizeHandle(handle string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(handle), "@"))
+}
+
+// Clean normalizes every field and returns them, or a *content.ValidationError listing
+// every field that is invalid.
+func (f Fields) Clean() (Fields, error) {
+	var violations []content.Violation
+
+	f.Handle = NormalizeHandle(f.Handle)
+	if f.Handle != "" && !handlePattern.MatchString(f.Handle) {
+		violations = append(violations, content.Violation{
+			Code:    "invalid_handle",
+			Message: "Handle must be 3 to 30 letters, digits or underscores.",
+		})
+	}
+
+	var err error
+	f.DisplayName, err = displayNamePipeline.Process(f.DisplayName)
+	violations = appendViolations(violations, "display_name", "Display name", err)
+	f.Bio, err = bioPipeline.Process(f.Bio)
+	violations = appendViolations(violations, "bio", "Bio", err)
+
+	f.AvatarURL = strings.TrimSpace(f.AvatarURL)
+	if f.AvatarURL != "" && !validAvatarURL(f.AvatarURL) {
+		violations = append(violations, content.Violation{
+			Code:    "invalid_avatar_url",
+			Message: "Avatar URL must be an absolute http or https URL.",
+		})
+	}
+
+	if len(violations) > 0 {
+		return Fields{}, &content.ValidationError{Violations: violations}
+	}
+	return f, nil
+}
+
+// appendViolations adds the violations in err, naming the field in their codes and messages.
+func appendViolations(violations []content.Violation, field, label string, err error) []content.Violation {
+	validationErr, ok := err.(*content.ValidationError)
+	if !ok {
+		return violations
+	}
+	for _, v := range validationErr.Violations {
+		v.Code = field + "_" + v.Code
+		v.Message = strings.Replace(v.Message, "Text", label, 1)
+		violations = append(violations, v)
+	}
+	return violations
+}
+
+func validAvatarURL(raw string) bool {
+	if len(raw) > MaxAvatarURLLength {
+		return false
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
@@ -0,0 +1,101 @@
+// Package worker runs background jobs, such as purging expired rows, on a fixed interval.
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// Job is one run of a background task.
+type Job func(ctx context.Context) error
+
+// Periodic runs Job every Interval.
+type Periodic struct {
+	Name     string
+	Interval time.Duration
+	Job      Job
+
+	mu      sync.Mutex
+	status  Status
+	started time.Time
+}
+
+// Status describes a Periodic's most recent run.
+type Status struct {
+	Running bool
+	LastRun time.Time
+	LastErr error
+}
+
+// Run calls Job right away and then every Interval until ctx is cancelled.
+// A failed run is logged and the next one goes ahead as planned.
+func (p *Periodic) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.Interval)
+	defer ticker.Stop()
+
+	p.setRunning(true)
+	defer p.setRunning(false)
+
+	for {
+		err := p.Job(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("%s: %v", p.Name, err)
+		}
+		p.finishRun(err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Status returns a snapshot of the worker's state.
+func (p *Periodic) Status() Status {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.status
+}
+
+// Check reports an error when the worker has stopped or has missed two runs in a
+// row, such as when a job hangs. A failed run is not an error here; the next one
+// may well succeed, and Status still reports it.
+func (p *Periodic) Check(ctx context.Context) error {
+	p.mu.Lock()
+	status, since := p.status, p.status.LastRun
+	if since.IsZero() {
+		since = p.started
+	}
+	p.mu.Unlock()
+
+	switch {
+	case !status.Running:
+		return fmt.Errorf("%s is not running", p.Name)
+	case time.Since(since) > 2*p.Interval:
+		return fmt.Errorf("%s has not finished a run since %s", p.Name, since.Format(time.RFC3339))
+	}
+	return nil
+}
+
+func (p *Periodic) setRunning(running bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.status.Running = running
+	if running {
+		p.started = time.Now()
+	}
+}
+
+func (p *Periodic) finishRun(err error) {
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.status.LastRun = time.Now()
+	p.status.LastErr = err
+}
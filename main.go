@@ -1,56 +1,266 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/Abo-Omar-74/httpServer/config"
 	"github.com/Abo-Omar-74/httpServer/handler"
+	"github.com/Abo-Omar-74/httpServer/internal/auth"
+	"github.com/Abo-Omar-74/httpServer/internal/content"
 	"github.com/Abo-Omar-74/httpServer/internal/database"
+	"github.com/Abo-Omar-74/httpServer/internal/health"
+	"github.com/Abo-Omar-74/httpServer/internal/lockout"
+	"github.com/Abo-Omar-74/httpServer/internal/mailer"
+	"github.com/Abo-Omar-74/httpServer/internal/migrate"
+	"github.com/Abo-Omar-74/httpServer/internal/storage"
+	"github.com/Abo-Omar-74/httpServer/internal/worker"
 	"github.com/Abo-Omar-74/httpServer/middleware"
+	"github.com/Abo-Omar-74/httpServer/sql/schema"
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
 )
 
-const (
-  port = "8080"
-  filepathRoot = "."
-)
-
-
-
 func main(){
   godotenv.Load()
 
-  jwtSecret := os.Getenv("JWT_SECRET")
-  if jwtSecret == ""{
-    log.Fatal("JWT_SECRET is not set")
+  // "migrate <command>" runs schema migrations and exits instead of serving.
+  args := os.Args[1:]
+  if len(args) > 0 && args[0] == "migrate"{
+    if len(args) < 2 || !slices.Contains(migrateCommands , args[1]){
+      log.Fatalf("usage: %s migrate %s [flags]" , filepath.Base(os.Args[0]) , strings.Join(migrateCommands , "|"))
+    }
+    // Migrations only need the database, so the rest of the configuration isn't required.
+    dbSettings , err := config.LoadDB(args[2:] , os.LookupEnv)
+    if errors.Is(err , flag.ErrHelp){
+      return
+    }
+    if err != nil{
+      log.Fatal(err)
+    }
+    db , err := openDatabase(*dbSettings)
+    if err != nil{
+      log.Fatal(err)
+    }
+    migrator , err := migrate.New(db , schema.FS)
+    if err == nil{
+      err = runMigration(migrator , args[1])
+    }
+    db.Close()
+    if err != nil{
+      log.Fatal(err)
+    }
+    return
   }
-  upgradePremiumKey := os.Getenv("API_KEY_UPGRADE_PREMIUM")
-  if upgradePremiumKey == ""{
-    log.Fatal("API_KEY_UPGRADE_PREMIUM is not set")
+
+  settings , err := config.Load(args , os.LookupEnv)
+  if errors.Is(err , flag.ErrHelp){
+    return
+  }
+  if err != nil{
+    log.Fatal(err)
   }
+  log.Printf("Loaded configuration:\n%s" , settings)
 
-  dbURL := os.Getenv("DB_URL")
-  db, err := sql.Open("postgres", dbURL)
-  
+  jwtKeys , err := loadJWTKeys(settings.JWT)
   if err != nil{
-    fmt.Print(err)
-    return
+    log.Fatal(err)
+  }
+
+  db , err := openDatabase(settings.DB)
+  if err != nil{
+    log.Fatal(err)
+  }
+
+  migrator , err := migrate.New(db , schema.FS)
+  if err != nil{
+    log.Fatal(err)
   }
+  if settings.DB.MigrateOnStart{
+    ran , err := migrator.Up(context.Background())
+    if err != nil{
+      log.Fatal(err)
+    }
+    for _ , migration := range ran{
+      log.Printf("Applied migration %s\n" , migration.Name)
+    }
+  } else {
+    pending , err := migrator.Pending(context.Background())
+    if err != nil{
+      log.Fatal(err)
+    }
+    if len(pending) > 0{
+      log.Fatalf("Database schema is missing %d migrations, starting with %s. Run \"migrate up\" or set DB_MIGRATE_ON_START=true" , len(pending) , pending[0].Name)
+    }
+  }
+
   dbQueries := database.New(db)
-  platform := os.Getenv("PLATFORM")
+
+  passwordHasher , err := auth.NewPasswordHasherByName(
+    settings.Password.Hasher,
+    &auth.BcryptHasher{Cost: settings.Password.BcryptCost},
+    &auth.Argon2idHasher{
+      Memory: uint32(settings.Password.Argon2MemoryKiB),
+      Iterations: uint32(settings.Password.Argon2Iterations),
+      Parallelism: uint8(settings.Password.Argon2Parallelism),
+      SaltLength: 16,
+      KeyLength: 32,
+    },
+  )
+  if err != nil{
+    log.Fatal(err)
+  }
+
+  appMailer , err := mailer.New(settings.Mailer.Kind , settings.Mailer.Dir)
+  if err != nil{
+    log.Fatal(err)
+  }
+
+  mediaStorage , err := storage.NewLocalStorage(settings.MediaDir , settings.BaseURL + "/media")
+  if err != nil{
+    log.Fatal(err)
+  }
+
+  var loginAttempts lockout.Store
+  switch settings.Login.AttemptStore{
+  case "memory":
+    loginAttempts = lockout.NewMemoryStore()
+  case "postgres":
+    loginAttempts = lockout.NewPostgresStore(dbQueries)
+  }
 
   apiCfg := config.ApiConfig{
     Db : dbQueries,
-    Platform: platform,
-    JwtSecret: jwtSecret,
-    UpgradePremiumKey: upgradePremiumKey,
+    SqlDB: db,
+    Platform: settings.Platform,
+    Jwt: &auth.JWTManager{
+      Keys: jwtKeys,
+      Issuer: settings.JWT.Issuer,
+      Audience: settings.JWT.Audience,
+      AccessTTL: settings.JWT.AccessTTL,
+      Leeway: settings.JWT.Leeway,
+    },
+    UpgradePremiumKey: settings.UpgradePremiumKey,
+    PasswordHasher: passwordHasher,
+    Mailer: appMailer,
+    BaseURL: settings.BaseURL,
+    EmailVerificationTTL: settings.Auth.EmailVerificationTTL,
+    PasswordResetTTL: settings.Auth.PasswordResetTTL,
+    TOTPIssuer: settings.Auth.TOTPIssuer,
+    TwoFactorChallengeTTL: settings.Auth.TwoFactorChallengeTTL,
+    PostContent: content.NewPipeline(
+      content.Normalize(),
+      content.NotEmpty(),
+      content.MaxLength(settings.Posts.MaxLength),
+      content.MaskBannedWords(settings.Posts.BannedWords),
+    ),
+    PostRestoreWindow: settings.Posts.RestoreWindow,
+    Storage: mediaStorage,
+    MaxAvatarBytes: settings.Uploads.MaxAvatarBytes,
+    MaxAttachmentBytes: settings.Uploads.MaxAttachmentBytes,
+    MaxAttachmentsPerPost: settings.Uploads.MaxAttachmentsPerPost,
+    LoginEmailGuard: &lockout.Guard{
+      Store: loginAttempts,
+      Prefix: "email:",
+      Policy: lockout.Policy{
+        FreeAttempts: settings.Login.FreeAttempts,
+        BaseDelay: settings.Login.BackoffBase,
+        MaxDelay: settings.Login.BackoffMax,
+        LockoutAfter: settings.Login.LockoutAfter,
+        LockoutDuration: settings.Login.LockoutDuration,
+        Window: settings.Login.FailureWindow,
+      },
+    },
+    // Many users can share an IP behind NAT, so it gets more room before blocking.
+    LoginIPGuard: &lockout.Guard{
+      Store: loginAttempts,
+      Prefix: "ip:",
+      Policy: lockout.Policy{
+        FreeAttempts: settings.Login.IPFreeAttempts,
+        BaseDelay: settings.Login.BackoffBase,
+        MaxDelay: settings.Login.BackoffMax,
+        LockoutAfter: settings.Login.IPLockoutAfter,
+        LockoutDuration: settings.Login.LockoutDuration,
+        Window: settings.Login.FailureWindow,
+      },
+    },
   }
 
+  // Deleted posts stay restorable for PostRestoreWindow, after which the purger removes them for good.
+  postPurger := &worker.Periodic{
+    Name: "post purger",
+    Interval: settings.Posts.PurgeInterval,
+    Job: func(ctx context.Context) error{
+      // The cutoff is computed in SQL so deleted_at and the window share the database clock.
+      restoreWindow := apiCfg.PostRestoreWindow.Seconds()
+      attachmentKeys , err := dbQueries.ListExpiredAttachmentKeys(ctx , restoreWindow)
+      if err != nil{
+        return err
+      }
+      purged , err := dbQueries.PurgeDeletedPosts(ctx , restoreWindow)
+      if err != nil{
+        return err
+      }
+      for _ , key := range attachmentKeys{
+        if err := mediaStorage.Delete(ctx , key); err != nil{
+          log.Printf("Failed to delete stored file %s: %v\n" , key , err)
+        }
+      }
+      if purged > 0{
+        log.Printf("Purged %d deleted posts\n" , purged)
+      }
+      return nil
+    },
+  }
+
+  // Readiness fails while the database, schema or workers are unusable, and from
+  // the moment shutdown starts.
+  readiness := health.NewChecker(settings.HTTP.HealthCheckTimeout)
+  readiness.Add("database" , db.PingContext)
+  readiness.Add("migrations" , func(ctx context.Context) error{
+    version , err := migrator.Version(ctx)
+    if err != nil{
+      return err
+    }
+    if version < migrator.Latest(){
+      return fmt.Errorf("schema is at version %d, want %d" , version , migrator.Latest())
+    }
+    return nil
+  })
+  readiness.Add("workers" , func(ctx context.Context) error{
+    if err := postPurger.Check(ctx); err != nil{
+      return err
+    }
+    // A failed purge is reported, but the next one may well succeed.
+    if lastErr := postPurger.Status().LastErr; lastErr != nil{
+      return health.Degraded(fmt.Errorf("%s: last run failed: %w" , postPurger.Name , lastErr))
+    }
+    return nil
+  })
+  apiCfg.Health = readiness
+
+  // Background workers run until shutdown cancels workersCtx.
+  workersCtx , stopWorkers := context.WithCancel(context.Background())
+  var workers sync.WaitGroup
+  workers.Add(1)
+  go func(){
+    defer workers.Done()
+    postPurger.Run(workersCtx)
+  }()
+
   apiHandler := &handler.Handler{
     Cfg: &apiCfg,
   }
@@ -64,29 +274,270 @@ func main(){
 
   mux.HandleFunc("POST /api/users" ,  apiHandler.CreateUserHandler)
   mux.HandleFunc("PUT /api/users" ,   apiMiddleware.MiddlewareAuth(apiHandler.EditUserHandler))
-  mux.HandleFunc("POST /admin/reset", apiHandler.DeleteAllUsers)
+  mux.HandleFunc("PUT /api/users/profile" ,   apiMiddleware.MiddlewareAuth(apiHandler.UpdateProfileHandler))
+  mux.HandleFunc("PATCH /api/users/profile" , apiMiddleware.MiddlewareAuth(apiHandler.UpdateProfileHandler))
+  mux.HandleFunc("PUT /api/users/avatar" , apiMiddleware.MiddlewareAuth(apiHandler.UploadAvatarHandler))
+  mux.HandleFunc("GET /api/users" , apiHandler.GetUserByHandleHandler)
+  mux.HandleFunc("GET /api/users/{userID}" , apiHandler.GetUserProfileHandler)
+  mux.HandleFunc("POST /api/users/verify" , apiHandler.VerifyEmailHandler)
+  mux.HandleFunc("POST /api/users/verify/resend" , apiMiddleware.MiddlewareAuth(apiHandler.ResendVerificationHandler))
+  mux.HandleFunc("POST /admin/reset", apiMiddleware.RequirePermission(auth.PermissionResetDatabase , apiHandler.DeleteAllUsers))
+  mux.HandleFunc("PUT /admin/users/{userID}/role" ,    apiMiddleware.RequirePermission(auth.PermissionManageRoles , apiHandler.GrantRoleHandler))
+  mux.HandleFunc("DELETE /admin/users/{userID}/role" , apiMiddleware.RequirePermission(auth.PermissionManageRoles , apiHandler.RevokeRoleHandler))
+  mux.HandleFunc("PUT /api/users/{userID}/follow" ,    apiMiddleware.MiddlewareAuth(apiHandler.FollowUserHandler))
+  mux.HandleFunc("DELETE /api/users/{userID}/follow" , apiMiddleware.MiddlewareAuth(apiHandler.UnfollowUserHandler))
+  mux.HandleFunc("GET /api/users/{userID}/followers" , apiHandler.GetFollowersHandler)
+  mux.HandleFunc("GET /api/users/{userID}/following" , apiHandler.GetFollowingHandler)
+  mux.HandleFunc("GET /api/timeline" , apiMiddleware.MiddlewareAuth(apiHandler.TimelineHandler))
   
   mux.HandleFunc("POST /api/login" ,  apiHandler.LoginHandler)
+  mux.HandleFunc("POST /api/login/2fa" , apiHandler.TwoFactorLoginHandler)
   mux.HandleFunc("POST /api/refresh", apiHandler.RefreshHandler)
   mux.HandleFunc("POST /api/revoke",  apiHandler.RevokeHandler)
 
+  mux.HandleFunc("POST /api/password/forgot" , apiHandler.ForgotPasswordHandler)
+  mux.HandleFunc("POST /api/password/reset" ,  apiHandler.ResetPasswordHandler)
+
+  mux.HandleFunc("POST /api/2fa/enroll" ,  apiMiddleware.MiddlewareAuth(apiHandler.EnrollTwoFactorHandler))
+  mux.HandleFunc("POST /api/2fa/confirm" , apiMiddleware.MiddlewareAuth(apiHandler.ConfirmTwoFactorHandler))
+  mux.HandleFunc("POST /api/2fa/recovery-codes" , apiMiddleware.MiddlewareAuth(apiHandler.RegenerateRecoveryCodesHandler))
+  mux.HandleFunc("POST /api/2fa/disable" , apiMiddleware.MiddlewareAuth(apiHandler.DisableTwoFactorHandler))
+
+  mux.HandleFunc("GET /api/sessions" ,    apiMiddleware.MiddlewareAuth(apiHandler.GetSessionsHandler))
+  mux.HandleFunc("DELETE /api/sessions" , apiMiddleware.MiddlewareAuth(apiHandler.DeleteAllSessionsHandler))
+  mux.HandleFunc("DELETE /api/sessions/{sessionID}" , apiMiddleware.MiddlewareAuth(apiHandler.DeleteSessionHandler))
+
   
-  mux.HandleFunc("POST /api/posts" , apiMiddleware.MiddlewareAuth(apiHandler.PostHandler))
-  mux.HandleFunc("GET /api/posts"  , apiHandler.GetPostsHandler)
-  mux.HandleFunc("GET /api/posts/{postID}"    , apiHandler.GetPostByIDHandler)
+  mux.HandleFunc("POST /api/posts" , apiMiddleware.RequireVerifiedEmail(apiHandler.PostHandler))
+  mux.HandleFunc("GET /api/posts"  , apiMiddleware.OptionalAuth(apiHandler.GetPostsHandler))
+  mux.HandleFunc("GET /api/posts/search" , apiMiddleware.OptionalAuth(apiHandler.SearchPostsHandler))
+  mux.HandleFunc("GET /api/posts/{postID}"    , apiMiddleware.OptionalAuth(apiHandler.GetPostByIDHandler))
   mux.HandleFunc("DELETE /api/posts/{postID}" , apiMiddleware.MiddlewareAuth(apiHandler.DeletePostHandler))
+  mux.HandleFunc("PUT /api/posts/{postID}"    , apiMiddleware.MiddlewareAuth(apiHandler.EditPostHandler))
+  mux.HandleFunc("PATCH /api/posts/{postID}"  , apiMiddleware.MiddlewareAuth(apiHandler.EditPostHandler))
+  mux.HandleFunc("POST /api/posts/{postID}/restore" , apiMiddleware.MiddlewareAuth(apiHandler.RestorePostHandler))
+  mux.HandleFunc("GET /api/posts/{postID}/revisions" , apiHandler.GetPostRevisionsHandler)
+  mux.HandleFunc("GET /api/posts/{postID}/replies" , apiMiddleware.OptionalAuth(apiHandler.GetRepliesHandler))
+  mux.HandleFunc("POST /api/posts/{postID}/attachments" , apiMiddleware.MiddlewareAuth(apiHandler.UploadAttachmentHandler))
+  mux.HandleFunc("DELETE /api/posts/{postID}/attachments/{attachmentID}" , apiMiddleware.MiddlewareAuth(apiHandler.DeleteAttachmentHandler))
+  mux.HandleFunc("PUT /api/posts/{postID}/reactions/{type}" ,    apiMiddleware.MiddlewareAuth(apiHandler.AddReactionHandler))
+  mux.HandleFunc("DELETE /api/posts/{postID}/reactions/{type}" , apiMiddleware.MiddlewareAuth(apiHandler.RemoveReactionHandler))
 
 
   mux.HandleFunc("POST /api/upgrade-premium/webhooks" , apiHandler.UpgradeUserHandler)
 
+  mux.HandleFunc("GET /.well-known/jwks.json" , apiHandler.JWKSHandler)
+
+  mux.HandleFunc("GET /healthz" , apiHandler.LivenessHandler)
+  mux.HandleFunc("GET /readyz" , apiHandler.ReadinessHandler)
+
+  mux.Handle("GET /media/" , http.StripPrefix("/media" , mediaStorage.Handler()))
+
 
 
   // Create http serve to handel incoming request with patterns set before
 
-  server := &http.Server{Addr : ":" + port , Handler : mux}
+  server := &http.Server{
+    Addr : ":" + settings.Port,
+    Handler : mux,
+    ReadHeaderTimeout: settings.HTTP.ReadHeaderTimeout,
+    ReadTimeout: settings.HTTP.ReadTimeout,
+    WriteTimeout: settings.HTTP.WriteTimeout,
+    IdleTimeout: settings.HTTP.IdleTimeout,
+  }
+  shutdownTimeout := settings.HTTP.ShutdownTimeout
+
+  signals , stopSignals := signal.NotifyContext(context.Background() , os.Interrupt , syscall.SIGTERM)
+
+  serveErr := make(chan error , 1)
+  go func(){
+    log.Printf("Serving media from %s on port: %s\n" , settings.MediaDir , settings.Port)
+    serveErr <- server.ListenAndServe()
+  }()
+
+  exitCode := 0
+  select {
+  case err := <-serveErr:
+    log.Printf("Server stopped: %v\n" , err)
+    exitCode = 1
+  case <-signals.Done():
+    log.Printf("Shutting down, draining requests for up to %s\n" , shutdownTimeout)
+  }
+  // A second signal kills the process right away.
+  stopSignals()
+
+  readiness.SetDraining()
+  if exitCode == 0 && settings.HTTP.DrainDelay > 0{
+    log.Printf("Failing readiness for %s before closing the listener\n" , settings.HTTP.DrainDelay)
+    time.Sleep(settings.HTTP.DrainDelay)
+  }
+
+  shutdownCtx , cancel := context.WithTimeout(context.Background() , shutdownTimeout)
+  if err := server.Shutdown(shutdownCtx); err != nil && !errors.Is(err , http.ErrServerClosed){
+    log.Printf("Requests still running at the shutdown deadline were cut off: %v\n" , err)
+    server.Close()
+    exitCode = 1
+  }
+
+  stopWorkers()
+  workersDone := make(chan struct{})
+  go func(){
+    workers.Wait()
+    apiCfg.Background.Wait()
+    close(workersDone)
+  }()
+  select {
+  case <-workersDone:
+  case <-shutdownCtx.Done():
+    log.Println("Background workers did not stop before the shutdown deadline")
+    exitCode = 1
+  }
+
+  if err := db.Close(); err != nil{
+    log.Printf("Failed to close the database: %v\n" , err)
+    exitCode = 1
+  }
+  cancel()
+  log.Println("Server stopped")
+  os.Exit(exitCode)
+}
+
+var migrateCommands = []string{"up" , "down" , "status" , "redo"}
+
+// runMigration runs one migrate subcommand against the database.
+func runMigration(migrator *migrate.Migrator , command string) error{
+  ctx := context.Background()
+  switch command{
+  case "up":
+    ran , err := migrator.Up(ctx)
+    for _ , migration := range ran{
+      log.Printf("Applied migration %s\n" , migration.Name)
+    }
+    if err != nil{
+      return err
+    }
+    if len(ran) == 0{
+      log.Println("No pending migrations")
+    }
+  case "down":
+    migration , err := migrator.Down(ctx)
+    if err != nil{
+      return err
+    }
+    log.Printf("Rolled back migration %s\n" , migration.Name)
+  case "redo":
+    migration , err := migrator.Redo(ctx)
+    if err != nil{
+      return err
+    }
+    log.Printf("Redid migration %s\n" , migration.Name)
+  case "status":
+    statuses , err := migrator.Status(ctx)
+    if err != nil{
+      return err
+    }
+    for _ , status := range statuses{
+      appliedAt := "pending"
+      if status.Applied{
+        appliedAt = status.AppliedAt.Format(time.RFC3339)
+      }
+      fmt.Printf("%-25s %s\n" , appliedAt , status.Migration.Name)
+    }
+  }
+  return nil
+}
 
-  log.Printf("Serving files %s on port: %s\n" , filepathRoot , port)
+// openDatabase opens the connection pool and pings it until the database answers.
+// Failed pings are retried with exponential backoff, and the last error is
+// returned once every attempt has failed.
+func openDatabase(settings config.DBSettings) (*sql.DB , error){
+  db , err := sql.Open("postgres" , settings.URL)
+  if err != nil{
+    return nil , fmt.Errorf("open database: %w" , err)
+  }
+  db.SetMaxOpenConns(settings.MaxOpenConns)
+  db.SetMaxIdleConns(settings.MaxIdleConns)
+  db.SetConnMaxLifetime(settings.ConnMaxLifetime)
+  db.SetConnMaxIdleTime(settings.ConnMaxIdleTime)
+
+  delay := settings.ConnectBackoffBase
+  for attempt := 1; ; attempt++{
+    ctx , cancel := context.WithTimeout(context.Background() , settings.ConnectTimeout)
+    err = db.PingContext(ctx)
+    cancel()
+    if err == nil{
+      return db , nil
+    }
+    if attempt >= settings.ConnectAttempts{
+      break
+    }
+    log.Printf("Database is not reachable (attempt %d of %d), retrying in %s: %v\n" , attempt , settings.ConnectAttempts , delay , err)
+    time.Sleep(delay)
+    delay = min(delay * 2 , settings.ConnectBackoffMax)
+  }
+  db.Close()
+  return nil , fmt.Errorf("database is unreachable after %d attempts: %w" , settings.ConnectAttempts , err)
+}
 
-  log.Fatal(server.ListenAndServe())
+// loadJWTKeys builds the JWT key set from the JWT settings.
+//
+// SigningKeys and VerificationKeys are "kid:path" entries of PEM private and
+// public keys. ActiveKeyID picks the signing key and defaults to the first
+// signing key. Secret, when set, stays valid as an HS256 key without a kid and
+// signs tokens only if no signing keys are given.
+func loadJWTKeys(settings config.JWTSettings) (*auth.KeySet , error){
+  keys := auth.NewKeySet()
 
-}
\ No newline at end of file
+  jwtSecret := settings.Secret
+  if jwtSecret != ""{
+    keys.Add(auth.NewHMACKey("" , []byte(jwtSecret)))
+  }
+
+  activeKid := settings.ActiveKeyID
+  for _ , spec := range settings.SigningKeys{
+    kid , path , found := strings.Cut(spec , ":")
+    if !found || kid == ""{
+      return nil , fmt.Errorf("JWT_SIGNING_KEYS entry %q must look like kid:path" , spec)
+    }
+    data , err := os.ReadFile(path)
+    if err != nil{
+      return nil , err
+    }
+    key , err := auth.ParsePrivateKeyPEM(kid , data)
+    if err != nil{
+      return nil , fmt.Errorf("JWT signing key %q: %w" , kid , err)
+    }
+    if err := keys.Add(key); err != nil{
+      return nil , err
+    }
+    if activeKid == ""{
+      activeKid = kid
+    }
+  }
+
+  for _ , spec := range settings.VerificationKeys{
+    kid , path , found := strings.Cut(spec , ":")
+    if !found || kid == ""{
+      return nil , fmt.Errorf("JWT_VERIFICATION_KEYS entry %q must look like kid:path" , spec)
+    }
+    data , err := os.ReadFile(path)
+    if err != nil{
+      return nil , err
+    }
+    key , err := auth.ParsePublicKeyPEM(kid , data)
+    if err != nil{
+      return nil , fmt.Errorf("JWT verification key %q: %w" , kid , err)
+    }
+    if err := keys.Add(key); err != nil{
+      return nil , err
+    }
+  }
+
+  if activeKid == "" && jwtSecret == ""{
+    return nil , fmt.Errorf("JWT_SECRET or JWT_SIGNING_KEYS must be set")
+  }
+  if err := keys.SetActive(activeKid); err != nil{
+    return nil , err
+  }
+  return keys , nil
+}
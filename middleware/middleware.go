@@ -27,13 +27,56 @@ func (m *Middleware) MiddlewareAuth(handler authedHandler) http.HandlerFunc{
 			helper.RespondWithError(w,http.StatusUnauthorized , "Unauthorized")
 			return
 		}
-		userID , err := auth.ValidateJWT(token , m.Cfg.JwtSecret)
+		claims , err := m.Cfg.Jwt.ValidateJWT(token)
 
 		if err != nil{
 			log.Printf("Invalid JWT: %v", err)
 			helper.RespondWithError(w,http.StatusUnauthorized ,"Unauthorized")
 			return
 		}
-		handler(w , r , userID)
+		userID , _ := claims.UserID()
+		handler(w , r.WithContext(auth.WithClaims(r.Context() , claims)) , userID)
+	}
+}
+
+// OptionalAuth stores the claims of a valid bearer token in the request context like MiddlewareAuth does.
+// Requests without one, including those with an expired or invalid token, are served anonymously.
+func (m *Middleware) OptionalAuth(handler http.HandlerFunc) http.HandlerFunc{
+	return func (w http.ResponseWriter , r *http.Request){
+		token , err := auth.GetBearerToken(r.Header)
+		if err != nil{
+			handler(w , r)
+			return
+		}
+		claims , err := m.Cfg.Jwt.ValidateJWT(token)
+		if err != nil{
+			handler(w , r)
+			return
+		}
+		handler(w , r.WithContext(auth.WithClaims(r.Context() , claims)))
 	}
-}
\ No newline at end of file
+}
+
+// RequirePermission authenticates the request and rejects users whose roles do not grant the permission.
+func (m *Middleware) RequirePermission(permission auth.Permission , handler authedHandler) http.HandlerFunc{
+	return m.MiddlewareAuth(func (w http.ResponseWriter , r *http.Request , userID uuid.UUID){
+		claims , ok := auth.ClaimsFromContext(r.Context())
+		if !ok || !auth.HasPermission(claims.Roles , permission){
+			helper.RespondWithError(w,http.StatusForbidden ,"Forbidden")
+			return
+		}
+		handler(w , r , userID)
+	})
+}
+
+// RequireVerifiedEmail authenticates the request and rejects users who haven't confirmed their email yet.
+func (m *Middleware) RequireVerifiedEmail(handler authedHandler) http.HandlerFunc{
+	return m.MiddlewareAuth(func (w http.ResponseWriter , r *http.Request , userID uuid.UUID){
+		claims , ok := auth.ClaimsFromContext(r.Context())
+		if !ok || !claims.EmailVerified{
+			helper.RespondWithError(w,http.StatusForbidden ,"Please verify your email address first.")
+			return
+		}
+		handler(w , r , userID)
+	})
+}
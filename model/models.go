@@ -1,7 +1,6 @@
 package model
 
 import (
-	"strings"
 	"time"
 
 	"github.com/Abo-Omar-74/httpServer/internal/database"
@@ -14,6 +13,12 @@ type User struct {
 	UpdatedAt time.Time `json:"updated_at"`
 	Email     string    `json:"email"`
 	IsPremium bool `json:"is_premium"`
+	Role      string `json:"role"`
+	EmailVerified bool `json:"email_verified"`
+	Handle      string `json:"handle"`
+	DisplayName string `json:"display_name"`
+	Bio         string `json:"bio"`
+	AvatarURL   string `json:"avatar_url"`
 }
 
 func DatabaseUserToUser(dbUser database.User) User{
@@ -23,6 +28,35 @@ func DatabaseUserToUser(dbUser database.User) User{
 		UpdatedAt: dbUser.UpdatedAt , 
 		Email: dbUser.Email , 
 		IsPremium: dbUser.IsPremium,
+		Role: dbUser.Role,
+		EmailVerified: dbUser.EmailVerifiedAt.Valid,
+		Handle: dbUser.Handle.String,
+		DisplayName: dbUser.DisplayName,
+		Bio: dbUser.Bio,
+		AvatarURL: dbUser.AvatarUrl,
+	}
+}
+
+// Profile is the public view of a user. It never includes the email address.
+type Profile struct{
+	ID          uuid.UUID `json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	Handle      string    `json:"handle"`
+	DisplayName string    `json:"display_name"`
+	Bio         string    `json:"bio"`
+	AvatarURL   string    `json:"avatar_url"`
+	IsPremium   bool      `json:"is_premium"`
+}
+
+func DatabaseUserToProfile(dbUser database.User) Profile{
+	return Profile{
+		ID: dbUser.ID,
+		CreatedAt: dbUser.CreatedAt,
+		Handle: dbUser.Handle.String,
+		DisplayName: dbUser.DisplayName,
+		Bio: dbUser.Bio,
+		AvatarURL: dbUser.AvatarUrl,
+		IsPremium: dbUser.IsPremium,
 	}
 }
 
@@ -32,29 +66,154 @@ type Post struct{
 	UpdatedAt time.Time `json:"updated_at"`
   Body string `json:"body"`
 	UserId uuid.UUID `json:"user_id"`
+	ParentID *uuid.UUID `json:"parent_id,omitempty"`
+	ReplyCount int64 `json:"reply_count"`
+	// Deleted marks a tombstone: a deleted post kept in place because it has replies.
+	// Its body and author are left out.
+	Deleted bool `json:"deleted,omitempty"`
+	Attachments []Attachment `json:"attachments"`
+	// Reactions counts the reactions on the post by type.
+	Reactions map[string]int64 `json:"reactions"`
+	// ViewerReacted and ViewerReactions tell whether, and with which types, the requesting user
+	// reacted. They are only filled in for authenticated requests.
+	ViewerReacted   bool     `json:"viewer_reacted"`
+	ViewerReactions []string `json:"viewer_reactions,omitempty"`
 }
 
 func DatabasePostToPost(dbPost database.Post) Post{
-	return Post{
-		dbPost.ID,
-		dbPost.CreatedAt,
-		dbPost.UpdatedAt,
-		dbPost.Body,
-		dbPost.UserID,
+	post := Post{
+		ID: dbPost.ID,
+		CreatedAt: dbPost.CreatedAt,
+		UpdatedAt: dbPost.UpdatedAt,
+		Body: dbPost.Body,
+		UserId: dbPost.UserID,
+		ParentID: nullUUIDToPointer(dbPost.ParentID),
+		Attachments: []Attachment{},
+		Reactions: map[string]int64{},
 	}
+	if dbPost.DeletedAt.Valid{
+		post.Body = ""
+		post.UserId = uuid.Nil
+		post.Deleted = true
+	}
+	return post
 }
 
+func nullUUIDToPointer(id uuid.NullUUID) *uuid.UUID{
+	if !id.Valid{
+		return nil
+	}
+	return &id.UUID
+}
 
-func DatabasePostsToPosts (dbPosts []database.Post , sortParam string) []Post{
-	var posts []Post
-	if strings.ToUpper(sortParam) == "DESC"{
-		for i:=len(dbPosts)-1;i>=0;i--{
-			posts = append(posts , DatabasePostToPost(dbPosts[i]))
-		}
-	}else {
-		for _ , post := range dbPosts{
-			posts = append(posts , DatabasePostToPost(post))
-		}
+
+func DatabasePostsToPosts (dbPosts []database.Post) []Post{
+	posts := []Post{}
+	for _ , post := range dbPosts{
+		posts = append(posts , DatabasePostToPost(post))
 	}
 	return posts
-}
\ No newline at end of file
+}
+
+// Attachment is an image uploaded to a post.
+type Attachment struct{
+	ID          uuid.UUID `json:"id"`
+	URL         string    `json:"url"`
+	ContentType string    `json:"content_type"`
+	SizeBytes   int64     `json:"size_bytes"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// DatabaseAttachmentToAttachment converts an attachment row, given the URL its file is served at.
+func DatabaseAttachmentToAttachment(dbAttachment database.PostAttachment , url string) Attachment{
+	return Attachment{
+		ID: dbAttachment.ID,
+		URL: url,
+		ContentType: dbAttachment.ContentType,
+		SizeBytes: dbAttachment.SizeBytes,
+		CreatedAt: dbAttachment.CreatedAt,
+	}
+}
+
+// PostsPage is one page of posts. NextCursor is empty on the last page.
+type PostsPage struct{
+	Posts      []Post `json:"posts"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// PostSearchResult is a post matching a search, with its relevance and a highlighted excerpt.
+// The snippet is HTML-escaped apart from the <mark> tags around matched words.
+type PostSearchResult struct{
+	Post
+	Rank    float32 `json:"rank"`
+	Snippet string  `json:"snippet"`
+}
+
+func DatabaseSearchRowToResult(row database.SearchPostsRow) PostSearchResult{
+	return PostSearchResult{
+		Post: Post{
+			ID: row.ID,
+			CreatedAt: row.CreatedAt,
+			UpdatedAt: row.UpdatedAt,
+			Body: row.Body,
+			UserId: row.UserID,
+			ParentID: nullUUIDToPointer(row.ParentID),
+			Attachments: []Attachment{},
+			Reactions: map[string]int64{},
+		},
+		Rank: row.Rank,
+		Snippet: row.Snippet,
+	}
+}
+
+// PostSearchPage is one page of search results. NextOffset is omitted on the last page.
+type PostSearchPage struct{
+	Results    []PostSearchResult `json:"results"`
+	NextOffset *int               `json:"next_offset,omitempty"`
+}
+
+// Follow is one entry in a follower or following list.
+type Follow struct{
+	UserID     uuid.UUID `json:"user_id"`
+	FollowedAt time.Time `json:"followed_at"`
+}
+
+// FollowsPage is one page of a follower or following list, newest first. NextCursor is empty on the last page.
+type FollowsPage struct{
+	Follows    []Follow `json:"follows"`
+	NextCursor string   `json:"next_cursor,omitempty"`
+}
+
+type PostRevision struct{
+	ID        uuid.UUID `json:"id"`
+	PostID    uuid.UUID `json:"post_id"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func DatabasePostRevisionToPostRevision(dbRevision database.PostRevision) PostRevision{
+	return PostRevision{
+		ID: dbRevision.ID,
+		PostID: dbRevision.PostID,
+		Body: dbRevision.Body,
+		CreatedAt: dbRevision.CreatedAt,
+	}
+}
+
+type Session struct{
+	ID         uuid.UUID `json:"id"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"`
+}
+
+func DatabaseSessionToSession(dbSession database.Session) Session{
+	return Session{
+		ID : dbSession.ID,
+		CreatedAt: dbSession.CreatedAt,
+		LastUsedAt: dbSession.LastUsedAt,
+		UserAgent: dbSession.UserAgent,
+		IPAddress: dbSession.IpAddress,
+	}
+}
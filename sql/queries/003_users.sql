@@ -16,7 +16,8 @@ where email = $1;
 
 -- name: EditUserByID :one
 UPDATE users 
-SET email = $2 , hashed_password = $3
+SET email = $2 , hashed_password = $3,
+  email_verified_at = CASE WHEN email = $2 THEN email_verified_at ELSE NULL END
 where id = $1
 RETURNING *;
 
@@ -24,4 +25,31 @@ RETURNING *;
 UPDATE users
 SET is_premium = TRUE
 WHERE id = $1
-RETURNING *;
\ No newline at end of file
+RETURNING *;
+
+-- name: UpdateUserPassword :exec
+UPDATE users
+SET hashed_password = $2, updated_at = NOW()
+WHERE id = $1;
+
+-- name: SetUserRole :one
+UPDATE users
+SET role = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: FindUserByHandle :one
+SELECT * from users
+where handle = $1;
+
+-- name: UpdateUserProfile :one
+UPDATE users
+SET handle = $2, display_name = $3, bio = $4, avatar_url = $5, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: SetUserAvatar :one
+UPDATE users
+SET avatar_url = $2, avatar_key = $3, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
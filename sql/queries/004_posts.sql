@@ -1,25 +1,75 @@
 -- name: CreatePost :one
-INSERT INTO posts(id, created_at, updated_at, body , user_id)
-VALUES (gen_random_uuid() , NOW() , NOW() , $1 , $2)
-RETURNING *;
-
--- name: GetAllPosts :many
-SELECT * 
-FROM posts
-ORDER BY created_at ASC;
+INSERT INTO posts(id, created_at, updated_at, body , user_id , parent_id)
+VALUES (gen_random_uuid() , NOW() , NOW() , $1 , $2 , $3)
+RETURNING id, created_at, updated_at, body, user_id, deleted_at, deleted_by, parent_id;
 
 -- name: GetPost :one
-SELECT * 
+SELECT id, created_at, updated_at, body, user_id, deleted_at, deleted_by, parent_id
 FROM posts
-where posts.id = $1;
+where posts.id = $1 AND posts.deleted_at IS NULL;
 
 -- name: DeletePost :one
-DELETE FROM posts
-WHERE id = $1
-RETURNING *;
+UPDATE posts
+SET deleted_at = NOW(), deleted_by = $2
+WHERE id = $1 AND deleted_at IS NULL
+RETURNING id, created_at, updated_at, body, user_id, deleted_at, deleted_by, parent_id;
+
+-- name: GetPostsByAuthorIDs :many
+SELECT id, created_at, updated_at, body, user_id, deleted_at, deleted_by, parent_id
+FROM posts
+WHERE posts.user_id = ANY(sqlc.arg('author_ids')::uuid[]) AND posts.deleted_at IS NULL
+  AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (posts.created_at, posts.id) < (sqlc.narg('cursor_created_at'), sqlc.narg('cursor_id')::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: ListPostsAsc :many
+SELECT id, created_at, updated_at, body, user_id, deleted_at, deleted_by, parent_id
+FROM posts
+WHERE posts.deleted_at IS NULL
+  AND (sqlc.narg('author_id')::uuid IS NULL OR posts.user_id = sqlc.narg('author_id'))
+  AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (posts.created_at, posts.id) > (sqlc.narg('cursor_created_at'), sqlc.narg('cursor_id')::uuid))
+ORDER BY created_at ASC, id ASC
+LIMIT sqlc.arg('limit');
 
--- name: GetPostsByAuthorID :many
-SELECT *
+-- name: ListPostsDesc :many
+SELECT id, created_at, updated_at, body, user_id, deleted_at, deleted_by, parent_id
 FROM posts
-WHERE posts.user_id = $1
-ORDER BY created_at ASC;
\ No newline at end of file
+WHERE posts.deleted_at IS NULL
+  AND (sqlc.narg('author_id')::uuid IS NULL OR posts.user_id = sqlc.narg('author_id'))
+  AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (posts.created_at, posts.id) < (sqlc.narg('cursor_created_at'), sqlc.narg('cursor_id')::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: SearchPosts :many
+SELECT posts.id, posts.created_at, posts.updated_at, posts.body, posts.user_id, posts.parent_id,
+  ts_rank(posts.search_vector, query)::real AS rank,
+  ts_headline('english',
+    replace(replace(replace(posts.body, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'),
+    query, 'StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MinWords=5, MaxWords=20')::text AS snippet
+FROM posts, websearch_to_tsquery('english', sqlc.arg('query')) AS query
+WHERE posts.search_vector @@ query
+  AND posts.deleted_at IS NULL
+  AND (sqlc.narg('author_id')::uuid IS NULL OR posts.user_id = sqlc.narg('author_id'))
+  AND (sqlc.narg('since')::timestamp IS NULL OR posts.created_at >= sqlc.narg('since'))
+  AND (sqlc.narg('until')::timestamp IS NULL OR posts.created_at < sqlc.narg('until'))
+ORDER BY rank DESC, posts.created_at DESC, posts.id DESC
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
+-- name: GetDeletedPost :one
+SELECT id, created_at, updated_at, body, user_id, deleted_at, deleted_by, parent_id
+FROM posts
+WHERE posts.id = $1 AND posts.deleted_at IS NOT NULL;
+
+-- name: RestorePost :one
+UPDATE posts
+SET deleted_at = NULL, deleted_by = NULL
+WHERE id = $1 AND deleted_at >= NOW() - make_interval(secs => sqlc.arg('restore_window_seconds')::float8)
+RETURNING id, created_at, updated_at, body, user_id, deleted_at, deleted_by, parent_id;
+
+-- name: PurgeDeletedPosts :execrows
+DELETE FROM posts
+WHERE deleted_at < NOW() - make_interval(secs => sqlc.arg('restore_window_seconds')::float8)
+  AND NOT EXISTS (SELECT 1 FROM posts AS replies WHERE replies.parent_id = posts.id);
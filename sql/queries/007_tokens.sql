@@ -1,6 +1,6 @@
 -- name: GenerateRefreshToken :one
-INSERT INTO refresh_tokens(token, created_at, updated_at, user_id , expires_at)
-VALUES ($1 , NOW() , NOW() , $2 , NOW() + INTERVAL '60 days')
+INSERT INTO refresh_tokens(token, created_at, updated_at, user_id , expires_at , family_id)
+VALUES ($1 , NOW() , NOW() , $2 , NOW() + INTERVAL '60 days' , $3)
 RETURNING *;
 
 -- name: GetRefreshToken :one
@@ -11,4 +11,14 @@ WHERE token = $1;
 -- name: RevokeRefreshToken :exec
 UPDATE refresh_tokens
 SET revoked_at = $1, updated_at = $2
-WHERE token = $3;
\ No newline at end of file
+WHERE token = $3;
+
+-- name: RotateRefreshToken :execrows
+UPDATE refresh_tokens
+SET revoked_at = NOW(), updated_at = NOW(), replaced_by = $2
+WHERE token = $1 AND revoked_at IS NULL;
+
+-- name: RevokeRefreshTokenFamily :exec
+UPDATE refresh_tokens
+SET revoked_at = NOW(), updated_at = NOW()
+WHERE family_id = $1 AND revoked_at IS NULL;
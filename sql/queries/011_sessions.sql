@@ -0,0 +1,42 @@
+-- name: CreateSession :one
+INSERT INTO sessions(id, created_at, updated_at, user_id, last_used_at, user_agent, ip_address)
+VALUES (gen_random_uuid() , NOW() , NOW() , $1 , NOW() , $2 , $3)
+RETURNING *;
+
+-- name: GetSession :one
+SELECT *
+FROM sessions
+WHERE id = $1;
+
+-- name: TouchSession :exec
+UPDATE sessions
+SET last_used_at = NOW(), updated_at = NOW(), user_agent = $2, ip_address = $3
+WHERE id = $1;
+
+-- name: GetActiveSessionsByUserID :many
+SELECT *
+FROM sessions
+WHERE sessions.user_id = $1
+  AND sessions.revoked_at IS NULL
+  AND EXISTS (
+    SELECT 1 FROM refresh_tokens
+    WHERE refresh_tokens.family_id = sessions.id
+      AND refresh_tokens.revoked_at IS NULL
+      AND refresh_tokens.expires_at > NOW()
+  )
+ORDER BY last_used_at DESC;
+
+-- name: RevokeSession :exec
+UPDATE sessions
+SET revoked_at = NOW(), updated_at = NOW()
+WHERE id = $1 AND revoked_at IS NULL;
+
+-- name: RevokeSessionsByUserID :exec
+UPDATE sessions
+SET revoked_at = NOW(), updated_at = NOW()
+WHERE user_id = $1 AND revoked_at IS NULL;
+
+-- name: RevokeRefreshTokensByUserID :exec
+UPDATE refresh_tokens
+SET revoked_at = NOW(), updated_at = NOW()
+WHERE user_id = $1 AND revoked_at IS NULL;
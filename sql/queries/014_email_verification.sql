@@ -0,0 +1,16 @@
+-- name: CreateEmailVerificationToken :one
+INSERT INTO email_verification_tokens(token_hash, created_at, user_id, email, expires_at)
+VALUES ($1 , NOW() , $2 , $3 , NOW() + make_interval(secs => sqlc.arg('ttl_seconds')::float8))
+RETURNING *;
+
+-- name: UseEmailVerificationToken :one
+UPDATE email_verification_tokens
+SET used_at = NOW()
+WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+RETURNING *;
+
+-- name: MarkUserEmailVerified :one
+UPDATE users
+SET email_verified_at = NOW(), updated_at = NOW()
+WHERE id = $1 AND email = $2
+RETURNING *;
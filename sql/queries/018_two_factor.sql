@@ -0,0 +1,54 @@
+-- name: UpsertTotpCredential :one
+INSERT INTO totp_credentials(user_id, created_at, secret)
+VALUES ($1 , NOW() , $2)
+ON CONFLICT (user_id) DO UPDATE
+SET created_at = NOW(), secret = EXCLUDED.secret, confirmed_at = NULL, last_used_step = 0
+RETURNING *;
+
+-- name: GetTotpCredential :one
+SELECT *
+FROM totp_credentials
+WHERE user_id = $1;
+
+-- name: ConfirmTotpCredential :exec
+UPDATE totp_credentials
+SET confirmed_at = NOW(), last_used_step = $2
+WHERE user_id = $1;
+
+-- name: UseTotpStep :execrows
+UPDATE totp_credentials
+SET last_used_step = $2
+WHERE user_id = $1 AND last_used_step < $2;
+
+-- name: DeleteTotpCredential :exec
+DELETE FROM totp_credentials
+WHERE user_id = $1;
+
+-- name: CreateRecoveryCode :exec
+INSERT INTO totp_recovery_codes(code_hash, created_at, user_id)
+VALUES ($1 , NOW() , $2);
+
+-- name: UseRecoveryCode :execrows
+UPDATE totp_recovery_codes
+SET used_at = NOW()
+WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL;
+
+-- name: DeleteRecoveryCodesByUserID :exec
+DELETE FROM totp_recovery_codes
+WHERE user_id = $1;
+
+-- name: CreateTwoFactorChallenge :one
+INSERT INTO two_factor_challenges(token_hash, created_at, user_id, expires_at)
+VALUES ($1 , NOW() , $2 , NOW() + make_interval(secs => sqlc.arg('ttl_seconds')::float8))
+RETURNING *;
+
+-- name: AttemptTwoFactorChallenge :one
+UPDATE two_factor_challenges
+SET attempts = attempts + 1
+WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+RETURNING *;
+
+-- name: UseTwoFactorChallenge :execrows
+UPDATE two_factor_challenges
+SET used_at = NOW()
+WHERE token_hash = $1 AND used_at IS NULL;
@@ -0,0 +1,29 @@
+-- name: RecordLoginFailure :one
+INSERT INTO login_attempts(key, failures, last_failure_at)
+VALUES ($1 , 1 , NOW())
+ON CONFLICT (key) DO UPDATE
+SET failures = CASE
+    WHEN login_attempts.last_failure_at < NOW() - make_interval(secs => sqlc.arg(window_seconds)::float8) THEN 1
+    ELSE login_attempts.failures + 1
+  END,
+  last_failure_at = NOW()
+RETURNING failures;
+
+-- name: LockLoginAttempts :exec
+INSERT INTO login_attempts(key, failures, last_failure_at, locked_until)
+VALUES ($1 , 0 , NOW() , NOW() + make_interval(secs => sqlc.arg(lock_seconds)::float8))
+ON CONFLICT (key) DO UPDATE
+SET locked_until = GREATEST(login_attempts.locked_until, EXCLUDED.locked_until);
+
+-- name: GetLoginRetryAfter :one
+SELECT EXTRACT(EPOCH FROM locked_until - NOW())::float8 AS retry_after_seconds
+FROM login_attempts
+WHERE key = $1 AND locked_until > NOW();
+
+-- name: DeleteLoginAttempts :exec
+DELETE FROM login_attempts
+WHERE key = $1;
+
+-- name: DeleteStaleLoginAttempts :exec
+DELETE FROM login_attempts
+WHERE last_failure_at < NOW() - make_interval(secs => sqlc.arg(window_seconds)::float8) AND (locked_until IS NULL OR locked_until < NOW());
@@ -0,0 +1,22 @@
+-- name: EditPost :one
+WITH previous AS (
+  SELECT id, body
+  FROM posts
+  WHERE posts.id = $1 AND posts.deleted_at IS NULL
+  FOR UPDATE
+), revision AS (
+  INSERT INTO post_revisions(id, created_at, post_id, body)
+  SELECT gen_random_uuid(), NOW(), previous.id, previous.body
+  FROM previous
+)
+UPDATE posts
+SET body = $2, updated_at = NOW()
+FROM previous
+WHERE posts.id = previous.id
+RETURNING posts.id, posts.created_at, posts.updated_at, posts.body, posts.user_id, posts.deleted_at, posts.deleted_by, posts.parent_id;
+
+-- name: GetPostRevisions :many
+SELECT *
+FROM post_revisions
+WHERE post_id = $1
+ORDER BY created_at DESC, id DESC;
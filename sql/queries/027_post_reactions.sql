@@ -0,0 +1,21 @@
+-- name: AddPostReaction :exec
+INSERT INTO post_reactions(post_id, user_id, type, created_at)
+VALUES ($1, $2, $3, NOW())
+ON CONFLICT DO NOTHING;
+
+-- name: DeletePostReaction :execrows
+DELETE FROM post_reactions
+WHERE post_id = $1 AND user_id = $2 AND type = $3;
+
+-- name: GetPostReactionCounts :many
+SELECT post_id, type, COUNT(*) AS count
+FROM post_reactions
+WHERE post_id = ANY(sqlc.arg('post_ids')::uuid[])
+GROUP BY post_id, type
+ORDER BY post_id, type;
+
+-- name: GetViewerPostReactions :many
+SELECT post_id, type
+FROM post_reactions
+WHERE user_id = $1 AND post_id = ANY(sqlc.arg('post_ids')::uuid[])
+ORDER BY post_id, type;
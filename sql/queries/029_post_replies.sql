@@ -0,0 +1,22 @@
+-- name: ListReplies :many
+SELECT id, created_at, updated_at, body, user_id, deleted_at, deleted_by, parent_id
+FROM posts
+WHERE posts.parent_id = sqlc.arg('parent_id')
+  AND (posts.deleted_at IS NULL
+    OR EXISTS (SELECT 1 FROM posts AS replies WHERE replies.parent_id = posts.id))
+  AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (posts.created_at, posts.id) > (sqlc.narg('cursor_created_at'), sqlc.narg('cursor_id')::uuid))
+ORDER BY created_at ASC, id ASC
+LIMIT sqlc.arg('limit');
+
+-- name: GetReplyCounts :many
+SELECT parent_id, COUNT(*) AS count
+FROM posts
+WHERE parent_id = ANY(sqlc.arg('post_ids')::uuid[]) AND deleted_at IS NULL
+GROUP BY parent_id;
+
+-- name: GetPostTombstone :one
+SELECT id, created_at, updated_at, body, user_id, deleted_at, deleted_by, parent_id
+FROM posts
+WHERE posts.id = $1 AND posts.deleted_at IS NOT NULL
+  AND EXISTS (SELECT 1 FROM posts AS replies WHERE replies.parent_id = posts.id);
@@ -0,0 +1,42 @@
+-- name: CreatePostAttachment :one
+INSERT INTO post_attachments(id, created_at, post_id, user_id, storage_key, content_type, size_bytes)
+VALUES (gen_random_uuid(), NOW(), $1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: CountPostAttachments :one
+SELECT COUNT(*)
+FROM post_attachments
+WHERE post_id = $1;
+
+-- name: LockPostForAttachments :one
+-- Held until the transaction ends, so concurrent uploads to one post count attachments one at a time.
+SELECT id
+FROM posts
+WHERE id = $1 AND deleted_at IS NULL
+FOR UPDATE;
+
+-- name: GetPostAttachments :many
+SELECT *
+FROM post_attachments
+WHERE post_id = ANY(sqlc.arg('post_ids')::uuid[])
+ORDER BY created_at ASC, id ASC;
+
+-- name: DeletePostAttachment :one
+DELETE FROM post_attachments
+WHERE id = $1 AND post_id = $2
+RETURNING *;
+
+-- name: ListExpiredAttachmentKeys :many
+SELECT post_attachments.storage_key
+FROM post_attachments
+JOIN posts ON posts.id = post_attachments.post_id
+WHERE posts.deleted_at < NOW() - make_interval(secs => sqlc.arg('restore_window_seconds')::float8)
+  AND NOT EXISTS (SELECT 1 FROM posts AS replies WHERE replies.parent_id = posts.id);
+
+-- name: ListStoredFileKeys :many
+SELECT avatar_key AS storage_key
+FROM users
+WHERE avatar_key <> ''
+UNION ALL
+SELECT storage_key
+FROM post_attachments;
@@ -0,0 +1,32 @@
+-- +goose Up
+CREATE TABLE sessions(
+  id uuid PRIMARY KEY,
+  created_at TIMESTAMP NOT NULL,
+  updated_at TIMESTAMP NOT NULL,
+  user_id uuid NOT NULL,
+  last_used_at TIMESTAMP NOT NULL,
+  user_agent VARCHAR NOT NULL DEFAULT '',
+  ip_address VARCHAR NOT NULL DEFAULT '',
+  revoked_at TIMESTAMP DEFAULT NULL,
+  FOREIGN KEY (user_id) REFERENCES
+  users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX sessions_user_id_idx ON sessions(user_id);
+
+-- Existing token families become sessions with unknown client details.
+INSERT INTO sessions(id, created_at, updated_at, user_id, last_used_at, revoked_at)
+SELECT family_id, MIN(created_at), MAX(updated_at), user_id, MAX(updated_at),
+  CASE WHEN BOOL_AND(revoked_at IS NOT NULL) THEN MAX(revoked_at) ELSE NULL END
+FROM refresh_tokens
+GROUP BY family_id, user_id;
+
+ALTER TABLE refresh_tokens
+ADD CONSTRAINT refresh_tokens_family_id_fkey
+FOREIGN KEY (family_id) REFERENCES sessions(id) ON DELETE CASCADE;
+
+-- +goose Down
+ALTER TABLE refresh_tokens
+DROP CONSTRAINT refresh_tokens_family_id_fkey;
+
+DROP TABLE sessions;
@@ -0,0 +1,23 @@
+-- +goose Up
+ALTER TABLE users
+ADD COLUMN email_verified_at TIMESTAMP DEFAULT NULL;
+
+-- Accounts created before verification existed are trusted as they are.
+UPDATE users SET email_verified_at = NOW();
+
+CREATE TABLE email_verification_tokens(
+  token_hash VARCHAR PRIMARY KEY,
+  created_at TIMESTAMP NOT NULL,
+  user_id uuid NOT NULL,
+  email VARCHAR NOT NULL,
+  expires_at TIMESTAMP NOT NULL,
+  used_at TIMESTAMP DEFAULT NULL,
+  FOREIGN KEY (user_id) REFERENCES
+  users(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE email_verification_tokens;
+
+ALTER TABLE users
+DROP COLUMN email_verified_at;
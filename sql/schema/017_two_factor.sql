@@ -0,0 +1,37 @@
+-- +goose Up
+CREATE TABLE totp_credentials(
+  user_id uuid PRIMARY KEY,
+  created_at TIMESTAMP NOT NULL,
+  secret VARCHAR NOT NULL,
+  confirmed_at TIMESTAMP DEFAULT NULL,
+  last_used_step BIGINT NOT NULL DEFAULT 0,
+  FOREIGN KEY (user_id) REFERENCES
+  users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE totp_recovery_codes(
+  code_hash VARCHAR PRIMARY KEY,
+  created_at TIMESTAMP NOT NULL,
+  user_id uuid NOT NULL,
+  used_at TIMESTAMP DEFAULT NULL,
+  FOREIGN KEY (user_id) REFERENCES
+  users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX totp_recovery_codes_user_id_idx ON totp_recovery_codes(user_id);
+
+CREATE TABLE two_factor_challenges(
+  token_hash VARCHAR PRIMARY KEY,
+  created_at TIMESTAMP NOT NULL,
+  user_id uuid NOT NULL,
+  expires_at TIMESTAMP NOT NULL,
+  attempts INTEGER NOT NULL DEFAULT 0,
+  used_at TIMESTAMP DEFAULT NULL,
+  FOREIGN KEY (user_id) REFERENCES
+  users(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE two_factor_challenges;
+DROP TABLE totp_recovery_codes;
+DROP TABLE totp_credentials;
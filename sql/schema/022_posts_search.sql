@@ -0,0 +1,12 @@
+-- +goose Up
+ALTER TABLE posts
+ADD COLUMN search_vector tsvector
+GENERATED ALWAYS AS (to_tsvector('english', body)) STORED;
+
+CREATE INDEX posts_search_vector_idx ON posts USING GIN(search_vector);
+
+-- +goose Down
+DROP INDEX posts_search_vector_idx;
+
+ALTER TABLE posts
+DROP COLUMN search_vector;
@@ -0,0 +1,14 @@
+-- +goose Up
+CREATE TABLE post_revisions(
+  id uuid PRIMARY KEY,
+  created_at TIMESTAMP NOT NULL,
+  post_id uuid NOT NULL,
+  body VARCHAR NOT NULL,
+  FOREIGN KEY (post_id) REFERENCES
+  posts(id) ON DELETE CASCADE
+);
+
+CREATE INDEX post_revisions_post_id_idx ON post_revisions(post_id, created_at);
+
+-- +goose Down
+DROP TABLE post_revisions;
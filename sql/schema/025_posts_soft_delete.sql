@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE posts
+ADD COLUMN deleted_at TIMESTAMP,
+ADD COLUMN deleted_by uuid;
+
+CREATE INDEX posts_deleted_at_idx ON posts(deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- +goose Down
+DROP INDEX posts_deleted_at_idx;
+
+ALTER TABLE posts
+DROP COLUMN deleted_by,
+DROP COLUMN deleted_at;
@@ -0,0 +1,17 @@
+-- +goose Up
+CREATE TABLE post_reactions(
+  post_id uuid NOT NULL,
+  user_id uuid NOT NULL,
+  type VARCHAR NOT NULL,
+  created_at TIMESTAMP NOT NULL,
+  PRIMARY KEY (post_id, user_id, type),
+  FOREIGN KEY (post_id) REFERENCES
+  posts(id) ON DELETE CASCADE,
+  FOREIGN KEY (user_id) REFERENCES
+  users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX post_reactions_user_id_idx ON post_reactions(user_id, post_id);
+
+-- +goose Down
+DROP TABLE post_reactions;
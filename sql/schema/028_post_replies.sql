@@ -0,0 +1,11 @@
+-- +goose Up
+ALTER TABLE posts
+ADD COLUMN parent_id uuid REFERENCES posts(id) ON DELETE SET NULL;
+
+CREATE INDEX posts_parent_id_idx ON posts(parent_id, created_at, id) WHERE parent_id IS NOT NULL;
+
+-- +goose Down
+DROP INDEX posts_parent_id_idx;
+
+ALTER TABLE posts
+DROP COLUMN parent_id;
@@ -0,0 +1,18 @@
+-- +goose Up
+CREATE TABLE follows(
+  follower_id uuid NOT NULL,
+  followee_id uuid NOT NULL,
+  created_at TIMESTAMP NOT NULL,
+  PRIMARY KEY (follower_id, followee_id),
+  CHECK (follower_id <> followee_id),
+  FOREIGN KEY (follower_id) REFERENCES
+  users(id) ON DELETE CASCADE,
+  FOREIGN KEY (followee_id) REFERENCES
+  users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX follows_followee_id_idx ON follows(followee_id, created_at);
+CREATE INDEX follows_follower_id_created_at_idx ON follows(follower_id, created_at);
+
+-- +goose Down
+DROP TABLE follows;
@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE users
+ADD COLUMN handle VARCHAR UNIQUE,
+ADD COLUMN display_name VARCHAR NOT NULL DEFAULT '',
+ADD COLUMN bio VARCHAR NOT NULL DEFAULT '',
+ADD COLUMN avatar_url VARCHAR NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE users
+DROP COLUMN avatar_url,
+DROP COLUMN bio,
+DROP COLUMN display_name,
+DROP COLUMN handle;
@@ -0,0 +1,27 @@
+-- +goose Up
+-- The storage key of an uploaded avatar. It is kept apart from avatar_url, which
+-- users can point anywhere, so only files we stored for this user get deleted.
+ALTER TABLE users
+ADD COLUMN avatar_key VARCHAR NOT NULL DEFAULT '';
+
+CREATE TABLE post_attachments(
+  id uuid PRIMARY KEY,
+  created_at TIMESTAMP NOT NULL,
+  post_id uuid NOT NULL,
+  user_id uuid NOT NULL,
+  storage_key VARCHAR NOT NULL UNIQUE,
+  content_type VARCHAR NOT NULL,
+  size_bytes BIGINT NOT NULL,
+  FOREIGN KEY (post_id) REFERENCES
+  posts(id) ON DELETE CASCADE,
+  FOREIGN KEY (user_id) REFERENCES
+  users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX post_attachments_post_id_idx ON post_attachments(post_id, created_at);
+
+-- +goose Down
+DROP TABLE post_attachments;
+
+ALTER TABLE users
+DROP COLUMN avatar_key;
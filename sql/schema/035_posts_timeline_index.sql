@@ -0,0 +1,8 @@
+-- +goose Up
+-- The timeline reads each followed author's newest posts first.
+CREATE INDEX posts_user_id_created_at_desc_idx ON posts(user_id, created_at DESC, id DESC);
+DROP INDEX posts_user_id_created_at_id_idx;
+
+-- +goose Down
+CREATE INDEX posts_user_id_created_at_id_idx ON posts(user_id, created_at, id);
+DROP INDEX posts_user_id_created_at_desc_idx;
@@ -0,0 +1,9 @@
+// Package schema embeds the goose migration files so the server binary can apply them itself.
+package schema
+
+import "embed"
+
+// FS holds the migration files, named like 001_users.sql.
+//
+//go:embed *.sql
+var FS embed.FS